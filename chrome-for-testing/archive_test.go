@@ -0,0 +1,210 @@
+// Copyright 2025 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenArchiveDispatch(t *testing.T) {
+	dir := t.TempDir()
+
+	zipPath := filepath.Join(dir, "archive.zip")
+	writeTestZip(t, zipPath, nil)
+	if _, err := os.Stat(zipPath); err != nil {
+		t.Fatal(err)
+	}
+
+	gzPath := filepath.Join(dir, "archive.tar.gz")
+	var gzBuf bytes.Buffer
+	gw := gzip.NewWriter(&gzBuf)
+	tw := tar.NewWriter(gw)
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(gzPath, gzBuf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	xzPath := filepath.Join(dir, "archive.tar.xz")
+	if err := os.WriteFile(xzPath, xzMagic, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dmgPath := filepath.Join(dir, "archive.dmg")
+	if err := os.WriteFile(dmgPath, []byte("not a real dmg but has the right extension"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	unknownPath := filepath.Join(dir, "archive.bin")
+	if err := os.WriteFile(unknownPath, []byte("just some bytes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		path    string
+		want    Archive
+		wantErr bool
+	}{
+		{zipPath, zipArchive{}, false},
+		{gzPath, tarGzArchive{}, false},
+		{xzPath, tarXzArchive{}, false},
+		{dmgPath, dmgArchive{}, false},
+		{unknownPath, nil, true},
+	} {
+		got, err := openArchive(tc.path)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("openArchive(%v): expected an error, got none", tc.path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("openArchive(%v): unexpected error: %v", tc.path, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("openArchive(%v) = %T, want %T", tc.path, got, tc.want)
+		}
+	}
+}
+
+// writeTestZip writes a zip archive at path containing a "prefix/"
+// top-level directory wrapping a regular file "prefix/file.txt" and,
+// for every name in symlinks, a symlink entry "prefix/<name>" pointing
+// at "file.txt".
+func writeTestZip(t *testing.T, path string, symlinks []string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+
+	fh := &zip.FileHeader{Name: "prefix/file.txt", Method: zip.Deflate}
+	fh.SetMode(0644)
+	w, err := zw.CreateHeader(fh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range symlinks {
+		lh := &zip.FileHeader{Name: "prefix/" + name, Method: zip.Store}
+		lh.SetMode(os.ModeSymlink | 0777)
+		lw, err := zw.CreateHeader(lh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := lw.Write([]byte("file.txt")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnzipPreservesSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "archive.zip")
+	writeTestZip(t, zipPath, []string{"link.txt"})
+
+	dst := filepath.Join(dir, "out")
+	if err := unzip(context.Background(), "prefix", zipPath, dst); err != nil {
+		t.Fatalf("unzip: %v", err)
+	}
+
+	linkPath := filepath.Join(dst, "link.txt")
+	info, err := os.Lstat(linkPath)
+	if err != nil {
+		t.Fatalf("Lstat(%v): %v", linkPath, err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("%v: expected a symlink, got mode %v", linkPath, info.Mode())
+	}
+	target, err := os.Readlink(linkPath)
+	if err != nil {
+		t.Fatalf("Readlink(%v): %v", linkPath, err)
+	}
+	if target != "file.txt" {
+		t.Errorf("Readlink(%v) = %q, want %q", linkPath, target, "file.txt")
+	}
+}
+
+func TestExtractTarPreservesSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "archive.tar")
+
+	f, err := os.Create(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(f)
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     "prefix/file.txt",
+		Typeflag: tar.TypeReg,
+		Mode:     0644,
+		Size:     5,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     "prefix/link.txt",
+		Typeflag: tar.TypeSymlink,
+		Linkname: "file.txt",
+		Mode:     0777,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := os.Open(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	dst := filepath.Join(dir, "out")
+	if err := extractTar(context.Background(), "prefix", r, src, dst); err != nil {
+		t.Fatalf("extractTar: %v", err)
+	}
+
+	linkPath := filepath.Join(dst, "link.txt")
+	info, err := os.Lstat(linkPath)
+	if err != nil {
+		t.Fatalf("Lstat(%v): %v", linkPath, err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("%v: expected a symlink, got mode %v", linkPath, info.Mode())
+	}
+	target, err := os.Readlink(linkPath)
+	if err != nil {
+		t.Fatalf("Readlink(%v): %v", linkPath, err)
+	}
+	if target != "file.txt" {
+		t.Errorf("Readlink(%v) = %q, want %q", linkPath, target, "file.txt")
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -17,21 +18,70 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"cloudeng.io/logging/ctxlog"
 	"github.com/google/uuid"
 )
 
 type CacheFlags struct {
-	RunnerTemp   string `subcmd:"runner-temp,,path to the runner temp directory if RUNNER_TEMP is not set"`
-	RunnerCache  string `subcmd:"runner-tool-cache,,path to the runner tool cache directory if RUNNER_TOOL_CACHE is not set"`
-	UUIDDownload bool   `subcmd:"uuid-download,true,'use a uuid for download cache files, if false, the download filename is fixed based on the url which is useful for testing'"`
+	RunnerTemp        string        `subcmd:"runner-temp,,path to the runner temp directory if RUNNER_TEMP is not set"`
+	RunnerCache       string        `subcmd:"runner-tool-cache,,path to the runner tool cache directory if RUNNER_TOOL_CACHE is not set"`
+	UUIDDownload      bool          `subcmd:"uuid-download,true,'use a uuid for download cache files, if false, the download filename is fixed based on the url which is useful for testing'"`
+	Verify            string        `subcmd:"verify,warn,'how to handle sha256 digest verification of downloaded artifacts: strict (fail on mismatch or missing digest), warn (log on mismatch), off (skip verification)'"`
+	ParallelDownloads int           `subcmd:"parallel-downloads,1,'number of concurrent byte-range requests to use for large downloads; 1 fetches the remaining bytes with a single request'"`
+	Retries           int           `subcmd:"retries,3,number of times to retry a failed download range before giving up"`
+	Resume            bool          `subcmd:"resume,true,'resume a partially completed ranged download using its .part.json sidecar file'"`
+	RetryBase         time.Duration `subcmd:"retry-base,500ms,base delay for jittered exponential backoff between download retries"`
+	RetryMax          time.Duration `subcmd:"retry-max,30s,maximum delay for jittered exponential backoff between download retries"`
+}
+
+// VerifyMode controls how a downloaded artifact's digest is checked
+// against the digest recorded for it in its SelectedDownload, as taken
+// from the provider's manifest or from chrome-lock.yaml.
+type VerifyMode int
+
+const (
+	VerifyWarn VerifyMode = iota
+	VerifyStrict
+	VerifyOff
+)
+
+func ParseVerifyMode(s string) (VerifyMode, error) {
+	switch s {
+	case "strict":
+		return VerifyStrict, nil
+	case "warn":
+		return VerifyWarn, nil
+	case "off":
+		return VerifyOff, nil
+	default:
+		return 0, fmt.Errorf("unknown verify mode: %q: use one of strict, warn, off", s)
+	}
+}
+
+func (v VerifyMode) String() string {
+	switch v {
+	case VerifyStrict:
+		return "strict"
+	case VerifyWarn:
+		return "warn"
+	case VerifyOff:
+		return "off"
+	default:
+		return "unknown"
+	}
 }
 
 type toolCache struct {
-	tempDir  string
-	cacheDir string
-	uuid     bool
+	tempDir           string
+	cacheDir          string
+	uuid              bool
+	parallelDownloads int
+	retries           int
+	resume            bool
+	retryBase         time.Duration
+	retryMax          time.Duration
 }
 
 var cwd string
@@ -70,6 +120,11 @@ func newToolCache(cf *CacheFlags) (*toolCache, error) {
 		tc.cacheDir = toAbs(cf.RunnerCache)
 	}
 	tc.uuid = cf.UUIDDownload
+	tc.parallelDownloads = cf.ParallelDownloads
+	tc.retries = cf.Retries
+	tc.resume = cf.Resume
+	tc.retryBase = cf.RetryBase
+	tc.retryMax = cf.RetryMax
 	return tc, nil
 }
 
@@ -88,14 +143,9 @@ func (tc toolCache) downloadPath(downloadURL string) (string, error) {
 }
 
 func (tc toolCache) applicationPaths(sd SelectedDownload) (prefix, binary, install string, err error) {
-	var specs map[Platform]installSpec
-	switch sd.Application {
-	case ApplicationChrome:
-		specs = chromeInstallSpecs
-	case ApplicationChromeDriver:
-		specs = chromeDriverInstallSpecs
-	default:
-		return "", "", "", fmt.Errorf("unknown application %q", sd.Application)
+	specs, err := installSpecsFor(sd.Browser, sd.Application)
+	if err != nil {
+		return "", "", "", err
 	}
 	spec, ok := specs[sd.Platform]
 	if !ok {
@@ -105,6 +155,7 @@ func (tc toolCache) applicationPaths(sd SelectedDownload) (prefix, binary, insta
 	binary = filepath.Join(
 		tc.cacheDir,
 		"setup-chrome",
+		sd.Browser.String(),
 		sd.Application.String(),
 		sd.Channel.String(),
 		spec.to,
@@ -114,6 +165,7 @@ func (tc toolCache) applicationPaths(sd SelectedDownload) (prefix, binary, insta
 	install = filepath.Join(
 		tc.cacheDir,
 		"setup-chrome",
+		sd.Browser.String(),
 		sd.Application.String(),
 		sd.Channel.String(),
 		spec.to,
@@ -124,24 +176,120 @@ func (tc toolCache) applicationPaths(sd SelectedDownload) (prefix, binary, insta
 	return
 }
 
+// installSpecsFor returns the per-platform install layout for browser
+// and application, i.e. where the desired binary lives within the
+// extracted archive.
+func installSpecsFor(browser Browser, application Application) (map[Platform]installSpec, error) {
+	switch browser {
+	case BrowserChrome:
+		switch application {
+		case ApplicationChrome:
+			return chromeInstallSpecs, nil
+		case ApplicationChromeDriver:
+			return chromeDriverInstallSpecs, nil
+		default:
+			return nil, fmt.Errorf("unknown application %q for browser %q", application, browser)
+		}
+	case BrowserFirefox:
+		return firefoxInstallSpecs, nil
+	case BrowserEdge:
+		return edgeInstallSpecs, nil
+	default:
+		return nil, fmt.Errorf("unknown browser %q", browser)
+	}
+}
+
 type installSpec struct {
 	from, to, binary string
 }
 
 var chromeInstallSpecs = map[Platform]installSpec{
-	PlatformLinux64: {"chrome-linux64", "x64", "chrome"},
-	PlatformWin64:   {"chrome-win64", "x64", "chrome.exe"},
+	PlatformLinux64:    {"chrome-linux64", "x64", "chrome"},
+	PlatformLinuxArm64: {"chrome-linux-arm64", "arm64", "chrome"},
+	PlatformWin64:      {"chrome-win64", "x64", "chrome.exe"},
 	PlatformMacArm64: {
 		"chrome-mac-arm64",
+		filepath.Join("arm64"),
+		filepath.Join("Google Chrome for Testing.app", "Contents", "MacOS", "Google Chrome for Testing"),
+	},
+	PlatformMacX64: {
+		"chrome-mac-x64",
 		filepath.Join("x64"),
 		filepath.Join("Google Chrome for Testing.app", "Contents", "MacOS", "Google Chrome for Testing"),
 	},
 }
 
 var chromeDriverInstallSpecs = map[Platform]installSpec{
-	PlatformLinux64:  {"chromedriver-linux64", "x64", "chromedriver"},
-	PlatformWin64:    {"chromedriver-win64", "x64", "chromedriver.exe"},
-	PlatformMacArm64: {"chromedriver-mac-arm64", "x64", "chromedriver"},
+	PlatformLinux64:    {"chromedriver-linux64", "x64", "chromedriver"},
+	PlatformLinuxArm64: {"chromedriver-linux-arm64", "arm64", "chromedriver"},
+	PlatformWin64:      {"chromedriver-win64", "x64", "chromedriver.exe"},
+	PlatformMacArm64:   {"chromedriver-mac-arm64", "arm64", "chromedriver"},
+	PlatformMacX64:     {"chromedriver-mac-x64", "x64", "chromedriver"},
+}
+
+// firefoxInstallSpecs and edgeInstallSpecs are best-effort: unlike
+// Chrome for Testing, neither browser publishes a stable, versioned
+// archive layout, so these assume the common case for each platform's
+// official build.
+var firefoxInstallSpecs = map[Platform]installSpec{
+	PlatformLinux64:    {"firefox", "x64", "firefox"},
+	PlatformLinuxArm64: {"firefox", "arm64", "firefox"},
+	PlatformWin64:      {"core", "x64", "firefox.exe"},
+	PlatformMacArm64:   {"Firefox.app", "arm64", filepath.Join("Firefox.app", "Contents", "MacOS", "firefox")},
+	PlatformMacX64:     {"Firefox.app", "x64", filepath.Join("Firefox.app", "Contents", "MacOS", "firefox")},
+}
+
+var edgeInstallSpecs = map[Platform]installSpec{
+	PlatformLinux64:  {"", "x64", "msedge"},
+	PlatformWin64:    {"", "x64", "msedge.exe"},
+	PlatformMacArm64: {"Microsoft Edge.app", "arm64", filepath.Join("Microsoft Edge.app", "Contents", "MacOS", "Microsoft Edge")},
+	PlatformMacX64:   {"Microsoft Edge.app", "x64", filepath.Join("Microsoft Edge.app", "Contents", "MacOS", "Microsoft Edge")},
+}
+
+// sha256File returns the lower-case hex encoded sha256 digest of the
+// file at path.
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("hashing %q: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// verifyDownload computes the sha256 digest of the file at path and
+// checks it against dl.SHA256 according to mode, returning the computed
+// digest regardless of whether verification was performed. A missing
+// expected digest is only an error in strict mode.
+func (tc toolCache) verifyDownload(ctx context.Context, mode VerifyMode, path string, dl Download) (string, error) {
+	logger := ctxlog.Logger(ctx)
+	digest, err := sha256File(path)
+	if err != nil {
+		return "", fmt.Errorf("computing sha256 for %q: %w", path, err)
+	}
+	if mode == VerifyOff {
+		return digest, nil
+	}
+	if dl.SHA256 == "" {
+		logger.Info("no expected sha256 digest available for download", "path", path, "digest", digest)
+		if mode == VerifyStrict {
+			return digest, fmt.Errorf("no expected sha256 digest available for %q and verify=strict", path)
+		}
+		return digest, nil
+	}
+	if !strings.EqualFold(digest, dl.SHA256) {
+		if mode == VerifyStrict {
+			return digest, fmt.Errorf("sha256 mismatch for %q: expected %q, got %q", path, dl.SHA256, digest)
+		}
+		logger.Info("sha256 mismatch", "path", path, "expected", dl.SHA256, "got", digest)
+		return digest, nil
+	}
+	logger.Info("sha256 verified", "path", path, "digest", digest)
+	return digest, nil
 }
 
 func (t toolCache) binaryExists(path string) bool {
@@ -184,6 +332,24 @@ func unzip(ctx context.Context, prefix, src, dst string) error {
 			}
 			continue
 		}
+		if f.Mode()&os.ModeSymlink != 0 {
+			// A zip symlink entry's "contents" is the link target text.
+			target, err := io.ReadAll(rc)
+			if err != nil {
+				return fmt.Errorf("reading symlink target for %q: %w", f.Name, err)
+			}
+			if err := rc.Close(); err != nil {
+				return fmt.Errorf("closing zip contents file %q: %w", f.Name, err)
+			}
+			logger.Debug("creating symlink", "zip_entry", f.Name, "stripped", stripped, "localized", localized, "destination", name)
+			if err := os.MkdirAll(filepath.Dir(name), 0700); err != nil {
+				return fmt.Errorf("creating directory for symlink %q: %w", name, err)
+			}
+			if err := os.Symlink(string(target), name); err != nil {
+				return fmt.Errorf("creating symlink %q -> %q: %w", name, target, err)
+			}
+			continue
+		}
 		logger.Debug("extracting file", "zip_entry", f.Name, "stripped", stripped, "localized", localized, "destination", name)
 
 		out, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, f.Mode())
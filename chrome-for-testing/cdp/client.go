@@ -0,0 +1,205 @@
+// Copyright 2025 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+// Package cdp is a minimal Chrome DevTools Protocol client: it confirms a
+// browser started with --remote-debugging-port is up, opens a WebSocket to
+// its debugger endpoint, and exchanges JSON-RPC commands and events over it.
+// It implements only what setup-chrome's own health-checking and automation
+// need, not the full CDP surface.
+package cdp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"nhooyr.io/websocket"
+)
+
+// versionInfo is the shape of the browser's /json/version response.
+type versionInfo struct {
+	Browser              string `json:"Browser"`
+	WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+}
+
+// Client is a connection to a browser's CDP WebSocket endpoint.
+type Client struct {
+	conn   *websocket.Conn
+	nextID atomic.Int64
+
+	mu       sync.Mutex
+	pending  map[int64]chan rpcResponse
+	subs     map[string][]chan json.RawMessage
+	closed   chan struct{}
+	closeErr error
+}
+
+type rpcRequest struct {
+	ID        int64  `json:"id"`
+	SessionID string `json:"sessionId,omitempty"`
+	Method    string `json:"method"`
+	Params    any    `json:"params,omitempty"`
+}
+
+// rpcResponse doubles as the shape of an incoming event: a reply has ID and
+// Result (or Error) set, an event has Method and Params set and no ID.
+type rpcResponse struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("cdp error %d: %s", e.Code, e.Message)
+}
+
+// Dial confirms that the browser listening for CDP connections on
+// 127.0.0.1:port is up via its /json/version endpoint, then opens a
+// WebSocket to the webSocketDebuggerUrl it reports.
+func Dial(ctx context.Context, port int) (*Client, error) {
+	versionURL := fmt.Sprintf("http://127.0.0.1:%d/json/version", port)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, versionURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %q: %w", versionURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %v from %q", resp.Status, versionURL)
+	}
+	var vi versionInfo
+	if err := json.NewDecoder(resp.Body).Decode(&vi); err != nil {
+		return nil, fmt.Errorf("decoding %q: %w", versionURL, err)
+	}
+	if vi.WebSocketDebuggerURL == "" {
+		return nil, fmt.Errorf("%q did not report a webSocketDebuggerUrl", versionURL)
+	}
+
+	conn, _, err := websocket.Dial(ctx, vi.WebSocketDebuggerURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("dialing %q: %w", vi.WebSocketDebuggerURL, err)
+	}
+	conn.SetReadLimit(64 << 20)
+
+	c := &Client{
+		conn:    conn,
+		pending: map[int64]chan rpcResponse{},
+		subs:    map[string][]chan json.RawMessage{},
+		closed:  make(chan struct{}),
+	}
+	go c.readLoop()
+	return c, nil
+}
+
+// Send issues method with params against the browser target and returns its
+// raw JSON result, blocking until the browser replies or ctx is done.
+func (c *Client) Send(ctx context.Context, method string, params any) (json.RawMessage, error) {
+	return c.send(ctx, "", method, params)
+}
+
+// SendSession is Send for a command directed at a specific target session,
+// as returned by Target.attachToTarget; it's required for domains such as
+// Page that operate on a single target rather than the browser as a whole.
+func (c *Client) SendSession(ctx context.Context, sessionID, method string, params any) (json.RawMessage, error) {
+	return c.send(ctx, sessionID, method, params)
+}
+
+func (c *Client) send(ctx context.Context, sessionID, method string, params any) (json.RawMessage, error) {
+	id := c.nextID.Add(1)
+	ch := make(chan rpcResponse, 1)
+	c.mu.Lock()
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	data, err := json.Marshal(rpcRequest{ID: id, SessionID: sessionID, Method: method, Params: params})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling %v request: %w", method, err)
+	}
+	if err := c.conn.Write(ctx, websocket.MessageText, data); err != nil {
+		return nil, fmt.Errorf("sending %v: %w", method, err)
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return nil, fmt.Errorf("%v: %w", method, resp.Error)
+		}
+		return resp.Result, nil
+	case <-c.closed:
+		return nil, fmt.Errorf("sending %v: connection closed: %w", method, c.closeErr)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Subscribe returns a channel on which the params of every subsequent event
+// whose method is event are delivered; events are dropped rather than
+// blocking readLoop if the subscriber falls behind. The channel is never
+// closed by Subscribe.
+func (c *Client) Subscribe(event string) <-chan json.RawMessage {
+	ch := make(chan json.RawMessage, 16)
+	c.mu.Lock()
+	c.subs[event] = append(c.subs[event], ch)
+	c.mu.Unlock()
+	return ch
+}
+
+// Close closes the underlying WebSocket connection.
+func (c *Client) Close() error {
+	return c.conn.Close(websocket.StatusNormalClosure, "")
+}
+
+func (c *Client) readLoop() {
+	ctx := context.Background()
+	for {
+		_, data, err := c.conn.Read(ctx)
+		if err != nil {
+			c.mu.Lock()
+			c.closeErr = err
+			c.mu.Unlock()
+			close(c.closed)
+			return
+		}
+		var resp rpcResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			continue
+		}
+		if resp.Method != "" {
+			c.mu.Lock()
+			subs := append([]chan json.RawMessage(nil), c.subs[resp.Method]...)
+			c.mu.Unlock()
+			for _, ch := range subs {
+				select {
+				case ch <- resp.Params:
+				default:
+				}
+			}
+			continue
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[resp.ID]
+		c.mu.Unlock()
+		if ok {
+			ch <- resp
+		}
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"runtime"
 	"runtime/debug"
 
 	"cloudeng.io/cmdutil/subcmd"
@@ -23,6 +22,14 @@ commands:
     summary: install a version of Chrome for Testing
   - name: user-data-dir
     summary: determine the user data dir for Chrome for Testing based on OS
+  - name: detect-driver
+    summary: detect the installed Chrome and select a matching chromedriver
+  - name: prune
+    summary: remove all but the N most-recently installed versions from the tool cache
+  - name: lock
+    summary: pin the resolved download for an application/channel/platform in chrome-lock.yaml
+  - name: run
+    summary: launch the installed browser, navigate to a URL and wait for it to load, then shut it down cleanly
 `
 
 func cli() *subcmd.CommandSetYAML {
@@ -31,6 +38,10 @@ func cli() *subcmd.CommandSetYAML {
 	cmd.Set("get-manifest").MustRunner((&endpointsCmd{}).Get, &endpointsFlags{})
 	cmd.Set("install").MustRunner(downloadInstallCmd.installCmd, &installFlags{})
 	cmd.Set("user-data-dir").MustRunner(downloadInstallCmd.userDataDirCmd, &installFlags{})
+	cmd.Set("detect-driver").MustRunner(detectDriverCmd{}.Get, &detectDriverFlags{})
+	cmd.Set("prune").MustRunner(pruneCmd{}.Get, &pruneFlags{})
+	cmd.Set("lock").MustRunner(lockCmd{}.Get, &lockFlags{})
+	cmd.Set("run").MustRunner(runCmd{}.Get, &runFlags{})
 	return cmd
 }
 
@@ -61,17 +72,9 @@ func main() {
 }
 
 func currentPlatform() string {
-	switch runtime.GOOS {
-	case "darwin":
-		if runtime.GOARCH == "arm64" {
-			return "mac-arm64"
-		}
-		return "mac-x64"
-	case "linux":
-		return "linux64"
-	case "windows":
-		return "win64"
-	default:
+	platform, err := DetectPlatform()
+	if err != nil {
 		return ""
 	}
+	return platform.String()
 }
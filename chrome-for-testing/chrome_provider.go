@@ -0,0 +1,38 @@
+// Copyright 2025 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// chromeProvider is the BrowserProvider backed by the Chrome for
+// Testing "last known good versions" manifest; it's the original,
+// and still default, provider for this tool.
+type chromeProvider struct{}
+
+func (chromeProvider) ListVersions(ctx context.Context) (any, error) {
+	ep := endpoints{}
+	return ep.getLastKnownGoodVersions(ctx)
+}
+
+func (chromeProvider) Resolve(ctx context.Context, rd RequestedDownload) (SelectedDownload, error) {
+	ep := endpoints{}
+	versions, err := ep.getLastKnownGoodVersions(ctx)
+	if err != nil {
+		return SelectedDownload{}, fmt.Errorf("failed getting last good versions: %w", err)
+	}
+	sd, err := versions.GetRequestedDownload(rd)
+	if err != nil {
+		return SelectedDownload{}, fmt.Errorf("getting selected download: %w", err)
+	}
+	sd.Browser = BrowserChrome
+	return sd, nil
+}
+
+func (chromeProvider) UserDataDir(goos string) (string, error) {
+	return getUserDataDir(goos)
+}
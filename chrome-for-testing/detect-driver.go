@@ -0,0 +1,63 @@
+// Copyright 2025 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"cloudeng.io/logging/ctxlog"
+)
+
+type detectDriverFlags struct {
+	Platform string `subcmd:"platform,,'platform to select a chromedriver for, e.g. linux64, mac-arm64, win64. Defaults to the current platform'"`
+}
+
+type detectDriverCmd struct{}
+
+// Get detects the Chrome installed on the local machine and selects the
+// chromedriver release that matches it by major version, falling back
+// to the nearest lower version when there is no exact match. This lets
+// CI pin a driver to whatever browser is already on the runner instead
+// of installing a matching Chrome as well.
+func (detectDriverCmd) Get(ctx context.Context, f any, _ []string) error {
+	fv := f.(*detectDriverFlags)
+	platformStr := fv.Platform
+	if platformStr == "" {
+		platformStr = currentPlatform()
+	}
+	platform, err := ParsePlatform(platformStr)
+	if err != nil {
+		return fmt.Errorf("parsing platform: %w", err)
+	}
+
+	detected, err := detectInstalledChromeVersion(ctx)
+	if err != nil {
+		return fmt.Errorf("detecting installed chrome: %w", err)
+	}
+	ctxlog.Info(ctx, "detected installed chrome", "version", detected)
+
+	ep := endpoints{}
+	versions, err := ep.getKnownGoodVersions(ctx)
+	if err != nil {
+		return fmt.Errorf("getting known good versions: %w", err)
+	}
+	sd, err := versions.SelectChromeDriverFor(detected, platform)
+	if err != nil {
+		return fmt.Errorf("selecting chromedriver: %w", err)
+	}
+
+	fmt.Printf("chrome %v -> chromedriver %v\n", detected, sd.Version)
+	if err := updateGithubActionOutput("chrome-version", detected); err != nil {
+		return fmt.Errorf("updating github action output: %w", err)
+	}
+	if err := updateGithubActionOutput("chromedriver-version", sd.Version); err != nil {
+		return fmt.Errorf("updating github action output: %w", err)
+	}
+	if err := updateGithubActionOutput("chromedriver-url", sd.Download.URL); err != nil {
+		return fmt.Errorf("updating github action output: %w", err)
+	}
+	return nil
+}
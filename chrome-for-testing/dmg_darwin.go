@@ -0,0 +1,45 @@
+// Copyright 2025 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+//go:build darwin
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"cloudeng.io/logging/ctxlog"
+)
+
+type dmgArchive struct{}
+
+// Extract mounts the dmg with hdiutil, relocates the prefix directory
+// from the mounted volume into dst, and unmounts it again.
+func (dmgArchive) Extract(ctx context.Context, prefix, src, dst string) error {
+	logger := ctxlog.Logger(ctx)
+	mountPoint, err := os.MkdirTemp("", "cft-dmg-mount-")
+	if err != nil {
+		return fmt.Errorf("creating dmg mount point: %w", err)
+	}
+	defer os.RemoveAll(mountPoint)
+
+	attach := exec.CommandContext(ctx, "hdiutil", "attach", src, "-mountpoint", mountPoint, "-nobrowse", "-quiet")
+	attach.Stderr = os.Stderr
+	if err := attach.Run(); err != nil {
+		return fmt.Errorf("running hdiutil attach %q: %w", src, err)
+	}
+	logger.Info("mounted dmg", "source", src, "mount_point", mountPoint)
+	defer func() {
+		detach := exec.CommandContext(ctx, "hdiutil", "detach", mountPoint, "-quiet")
+		detach.Stderr = os.Stderr
+		if err := detach.Run(); err != nil {
+			logger.Info("failed to detach dmg", "mount_point", mountPoint, "error", err)
+		}
+	}()
+
+	return movePrefixed(prefix, mountPoint, dst)
+}
@@ -0,0 +1,19 @@
+// Copyright 2025 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+//go:build !darwin
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+)
+
+type dmgArchive struct{}
+
+func (dmgArchive) Extract(_ context.Context, _, src, _ string) error {
+	return fmt.Errorf("extracting dmg archive %q: not supported on %v", src, runtime.GOOS)
+}
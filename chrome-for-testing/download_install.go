@@ -18,15 +18,19 @@ import (
 
 	"cloudeng.io/file/diskusage"
 	"cloudeng.io/logging/ctxlog"
-	"cloudeng.io/net/http/httpfs"
 )
 
 type VersionFlags struct {
-	Channel     string `subcmd:"channel,stable,chrome for testing channel channel to install"`
-	Platform    string `subcmd:"platform,,'platform to install chrome for testing for, e.g. linux64, mac-arm64, win64'"`
+	Browser     string `subcmd:"browser,chrome,'browser to install, one of chrome, firefox, edge'"`
+	Channel     string `subcmd:"channel,stable,channel to install"`
+	Platform    string `subcmd:"platform,,'platform to install for, e.g. linux64, mac-arm64, win64'"`
 	Application string `subcmd:"application,chrome,'name of the application to install, e.g. chrome, chromedriver'"`
 }
 
+func (vf *VersionFlags) ParseBrowser() (Browser, error) {
+	return ParseBrowser(vf.Browser)
+}
+
 func (vf *VersionFlags) ParseRequestedDownload() (RequestedDownload, error) {
 	var rd RequestedDownload
 	if vf.Platform == "" {
@@ -55,15 +59,24 @@ func (vf *VersionFlags) ParseRequestedDownload() (RequestedDownload, error) {
 type installFlags struct {
 	VersionFlags
 	CacheFlags
-	Debug      bool `subcmd:"debug,false,eenable debug output"`
-	Initialize bool `subcmd:"initialize,false,initialize browser profile after installation"`
+	Debug         bool `subcmd:"debug,false,eenable debug output"`
+	Initialize    bool `subcmd:"initialize,false,initialize browser profile after installation"`
+	DebuggingPort int  `subcmd:"debugging-port,9222,'remote debugging port to launch the browser on when --initialize is set'"`
 }
 
 type downloadInstallCmd struct{}
 
 func (ic *downloadInstallCmd) installCmd(ctx context.Context, f any, args []string) error {
 	fv := f.(*installFlags)
-	sd, err := ic.getSelectedDownload(ctx, fv.VersionFlags)
+	requestedBrowser, err := fv.VersionFlags.ParseBrowser()
+	if err != nil {
+		return fmt.Errorf("invalid browser: %w", err)
+	}
+	provider, err := providerFor(requestedBrowser)
+	if err != nil {
+		return err
+	}
+	sd, lockedTo, err := ic.getSelectedDownload(ctx, provider, requestedBrowser, fv.VersionFlags)
 	if err != nil {
 		return fmt.Errorf("getting download: %w", err)
 	}
@@ -81,25 +94,63 @@ func (ic *downloadInstallCmd) installCmd(ctx context.Context, f any, args []stri
 		return fmt.Errorf("creating tool cache: %w", err)
 	}
 
+	verifyMode, err := ParseVerifyMode(fv.Verify)
+	if err != nil {
+		return fmt.Errorf("invalid verify mode: %w", err)
+	}
+	if lockedTo {
+		// chrome-lock.yaml exists specifically to protect against a
+		// compromised or changed upstream artifact, so its digest is
+		// enforced regardless of --verify, provided the lock entry
+		// actually recorded one: not every browser's upstream manifest
+		// publishes a per-file digest (Chrome and Firefox don't), so a
+		// locked entry without one can't be strictly verified.
+		if sd.Download.SHA256 != "" {
+			logger.Info("using chrome-lock.yaml pinned download", "version", sd.Version, "url", sd.Download.URL)
+			verifyMode = VerifyStrict
+		} else {
+			logger.Info("using chrome-lock.yaml pinned download (no sha256 recorded, verification left at --verify)", "version", sd.Version, "url", sd.Download.URL)
+		}
+	}
+
 	prefix, binaryPath, installDir, err := cache.applicationPaths(sd)
 	if err != nil {
 		return fmt.Errorf("getting application paths: %w", err)
 	}
 
-	if !cache.binaryExists(binaryPath) {
+	if cachedPath, hit := cache.Resolve(sd); hit {
+		logger.Info("using cached install", "binary", cachedPath, "version", sd.Version)
+		binaryPath = cachedPath
+	} else {
 		downloadPath, err := ic.download(ctx, cache, sd)
 		if err != nil {
 			return fmt.Errorf("downloading file: %w", err)
 		}
 
+		digest, err := cache.verifyDownload(ctx, verifyMode, downloadPath, sd.Download)
+		if err != nil {
+			return fmt.Errorf("verifying download: %w", err)
+		}
+		if err := updateGithubActionOutput("chrome-sha256", digest); err != nil {
+			return fmt.Errorf("updating github action output: %w", err)
+		}
+
+		archive, err := openArchive(downloadPath)
+		if err != nil {
+			return fmt.Errorf("identifying archive format: %w", err)
+		}
 		fmt.Printf("Extracting download %q to %q (prefix: %q)\n", downloadPath, installDir, prefix)
-		if err := unzip(ctx, prefix, downloadPath, installDir); err != nil {
-			return fmt.Errorf("unzipping download: %w", err)
+		if err := archive.Extract(ctx, prefix, downloadPath, installDir); err != nil {
+			return fmt.Errorf("extracting download: %w", err)
 		}
 
 		if err := prepareInstallDir(ctx, installDir); err != nil {
 			return fmt.Errorf("preparing install dir: %w", err)
 		}
+
+		if err := cache.recordInstall(sd, installDir, binaryPath, digest); err != nil {
+			return fmt.Errorf("recording install: %w", err)
+		}
 	}
 
 	version, err := getVersion(ctx, fv.Debug, binaryPath)
@@ -116,7 +167,7 @@ func (ic *downloadInstallCmd) installCmd(ctx context.Context, f any, args []stri
 		return nil
 	}
 
-	userDataDir, err := getUserDataDir(runtime.GOOS)
+	userDataDir, err := provider.UserDataDir(runtime.GOOS)
 	if err != nil {
 		return fmt.Errorf("determining user data dir: %w", err)
 	}
@@ -125,10 +176,11 @@ func (ic *downloadInstallCmd) installCmd(ctx context.Context, f any, args []stri
 	}
 
 	browser := browser{
-		goos:        runtime.GOOS,
-		binaryPath:  binaryPath,
-		userDataDir: userDataDir,
-		debug:       fv.Debug,
+		goos:          runtime.GOOS,
+		binaryPath:    binaryPath,
+		userDataDir:   userDataDir,
+		debuggingPort: fv.DebuggingPort,
+		debug:         fv.Debug,
 	}
 	logger.Info("initializing browser profile", "user_data_dir", userDataDir)
 	if err := browser.init(ctx, 30*time.Second); err != nil {
@@ -148,9 +200,9 @@ func (ic *downloadInstallCmd) download(ctx context.Context, cache *toolCache, sd
 	logger := ctxlog.Logger(ctx)
 	logger.Info("downloading file", "url", sd.Download.URL, "path", downloadPath)
 	start := time.Now()
-	downloader := httpfs.NewDownloader().
-		WithReaderOptions(httpfs.WithLargeFileBlockSize(64 * 1024 * 1024))
-	n, err := downloader.DownloadFile(ctx, sd.Download.URL, downloadPath)
+
+	rd := newRangedDownloader(cache.parallelDownloads, cache.retries, cache.resume, cache.retryBase, cache.retryMax)
+	n, err := rd.download(ctx, sd.Download.URL, downloadPath)
 	if err != nil {
 		return "", fmt.Errorf("downloading %q: %w", sd.Download.URL, err)
 	}
@@ -159,22 +211,30 @@ func (ic *downloadInstallCmd) download(ctx context.Context, cache *toolCache, sd
 	return downloadPath, nil
 }
 
-func (downloadInstallCmd) getSelectedDownload(ctx context.Context, vf VersionFlags) (SelectedDownload, error) {
+// getSelectedDownload resolves the download for vf through provider,
+// honoring chrome-lock.yaml if it has a matching entry for browser
+// rather than re-querying the provider's manifest, and reports
+// whether the result came from the lock.
+func (downloadInstallCmd) getSelectedDownload(ctx context.Context, provider BrowserProvider, browser Browser, vf VersionFlags) (SelectedDownload, bool, error) {
 	var sd SelectedDownload
 	rd, err := vf.ParseRequestedDownload()
 	if err != nil {
-		return sd, fmt.Errorf("invalid requested download: %w", err)
+		return sd, false, fmt.Errorf("invalid requested download: %w", err)
 	}
-	ep := endpoints{}
-	versions, err := ep.getLastKnownGoodVersions(ctx)
+
+	lf, err := loadLockFile()
 	if err != nil {
-		return sd, fmt.Errorf("failed getting last good versions: %w", err)
+		return sd, false, err
+	}
+	if entry, ok := lf.find(browser.String(), rd.Application.String(), rd.Channel.String(), rd.Platform.String()); ok {
+		return entry.toSelectedDownload(), true, nil
 	}
-	sd, err = versions.GetRequestedDownload(rd)
+
+	sd, err = provider.Resolve(ctx, rd)
 	if err != nil {
-		return sd, fmt.Errorf("getting selected download: %w", err)
+		return sd, false, fmt.Errorf("getting selected download: %w", err)
 	}
-	return sd, nil
+	return sd, false, nil
 }
 
 func getVersion(ctx context.Context, debug bool, binaryPath string) (string, error) {
@@ -0,0 +1,176 @@
+// Copyright 2025 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"cloudeng.io/webapi/operations"
+)
+
+// edgeProduct is one channel's entry in the Edge Updates enterprise
+// feed, e.g. {Product: "Stable", Releases: [...]}.
+type edgeProduct struct {
+	Product  string        `json:"Product"`
+	Releases []edgeRelease `json:"Releases"`
+}
+
+type edgeRelease struct {
+	Platform       string         `json:"Platform"`
+	Architecture   string         `json:"Architecture"`
+	ProductVersion string         `json:"ProductVersion"`
+	Artifacts      []edgeArtifact `json:"Artifacts"`
+}
+
+type edgeArtifact struct {
+	ArtifactName  string `json:"ArtifactName"`
+	Location      string `json:"Location"`
+	Hash          string `json:"Hash"`
+	HashAlgorithm string `json:"HashAlgorithm"`
+	SizeInBytes   int64  `json:"SizeInBytes"`
+}
+
+type edgeProvider struct{}
+
+func (edgeProvider) ListVersions(ctx context.Context) (any, error) {
+	const edgeUpdatesEndpoint = "https://edgeupdates.microsoft.com/api/products?view=enterprise"
+	ep := operations.NewEndpoint[*[]edgeProduct]()
+	products, _, _, err := ep.Get(ctx, edgeUpdatesEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func edgeProductName(channel Channel) (string, error) {
+	switch channel {
+	case ChannelStable:
+		return "Stable", nil
+	case ChannelBeta:
+		return "Beta", nil
+	case ChannelDev:
+		return "Dev", nil
+	case ChannelCanary:
+		return "Canary", nil
+	default:
+		return "", fmt.Errorf("unsupported edge channel %v", channel)
+	}
+}
+
+func edgePlatformArch(platform Platform) (plat, arch string, err error) {
+	switch platform {
+	case PlatformLinux64:
+		return "Linux", "x64", nil
+	case PlatformMacArm64:
+		return "MacOS", "arm64", nil
+	case PlatformMacX64:
+		return "MacOS", "x64", nil
+	case PlatformWin64:
+		return "Windows", "x64", nil
+	default:
+		return "", "", fmt.Errorf("unsupported edge platform %v", platform)
+	}
+}
+
+// bestEdgeArtifact picks the artifact closest to the archive formats
+// this tool knows how to extract (see archive.go); Edge for Testing
+// mostly ships platform installers (.msi, .pkg, .deb, .rpm) rather
+// than plain archives, so this is best-effort and prefers a "zip"
+// flavoured artifact when the release publishes one. It's an error
+// for none of the artifacts to be a zip: the installer formats aren't
+// something openArchive knows how to extract, so silently picking one
+// would only turn into a confusing failure later on.
+func bestEdgeArtifact(artifacts []edgeArtifact) (edgeArtifact, error) {
+	if len(artifacts) == 0 {
+		return edgeArtifact{}, fmt.Errorf("no artifacts published for this edge release")
+	}
+	for _, a := range artifacts {
+		if strings.Contains(strings.ToLower(a.ArtifactName), "zip") {
+			return a, nil
+		}
+	}
+	return edgeArtifact{}, fmt.Errorf("no zip artifact among: %v", artifactNames(artifacts))
+}
+
+func artifactNames(artifacts []edgeArtifact) []string {
+	names := make([]string, len(artifacts))
+	for i, a := range artifacts {
+		names[i] = a.ArtifactName
+	}
+	return names
+}
+
+func (edgeProvider) Resolve(ctx context.Context, rd RequestedDownload) (SelectedDownload, error) {
+	if rd.Application != ApplicationChrome {
+		return SelectedDownload{}, fmt.Errorf("edge only supports the browser itself, not %q", rd.Application)
+	}
+	productName, err := edgeProductName(rd.Channel)
+	if err != nil {
+		return SelectedDownload{}, err
+	}
+	plat, arch, err := edgePlatformArch(rd.Platform)
+	if err != nil {
+		return SelectedDownload{}, err
+	}
+
+	ep := edgeProvider{}
+	raw, err := ep.ListVersions(ctx)
+	if err != nil {
+		return SelectedDownload{}, fmt.Errorf("failed getting edge updates manifest: %w", err)
+	}
+	products, ok := raw.(*[]edgeProduct)
+	if !ok || products == nil {
+		return SelectedDownload{}, fmt.Errorf("unexpected edge updates manifest shape")
+	}
+
+	for _, p := range *products {
+		if !strings.EqualFold(p.Product, productName) {
+			continue
+		}
+		for _, r := range p.Releases {
+			if !strings.EqualFold(r.Platform, plat) || !strings.EqualFold(r.Architecture, arch) {
+				continue
+			}
+			artifact, err := bestEdgeArtifact(r.Artifacts)
+			if err != nil {
+				return SelectedDownload{}, fmt.Errorf("release %v %v/%v: %w", r.ProductVersion, plat, arch, err)
+			}
+			return SelectedDownload{
+				Browser:     BrowserEdge,
+				Platform:    rd.Platform,
+				Channel:     rd.Channel,
+				Application: rd.Application,
+				Version:     r.ProductVersion,
+				Download: Download{
+					Platform: rd.Platform.String(),
+					URL:      artifact.Location,
+					SHA256:   artifact.Hash,
+					Size:     artifact.SizeInBytes,
+				},
+			}, nil
+		}
+	}
+	return SelectedDownload{}, fmt.Errorf("no edge release found for channel %v, platform %v/%v", productName, plat, arch)
+}
+
+func (edgeProvider) UserDataDir(goos string) (string, error) {
+	home, err := homeDirFor(goos)
+	if err != nil {
+		return "", err
+	}
+	switch goos {
+	case "linux":
+		return filepath.Join(home, ".config", "microsoft-edge"), nil
+	case "darwin":
+		return filepath.Join(home, "Library", "Application Support", "Microsoft Edge"), nil
+	case "windows":
+		return filepath.Join(home, "Microsoft", "Edge", "User Data"), nil
+	default:
+		return "", fmt.Errorf("unsupported platform %q", goos)
+	}
+}
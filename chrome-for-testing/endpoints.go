@@ -14,7 +14,8 @@ import (
 )
 
 type endpointsFlags struct {
-	Output string `subcmd:"output,,output file for endpoints data (defaults to stdout)"`
+	Browser string `subcmd:"browser,chrome,'browser whose manifest to retrieve, one of chrome, firefox, edge'"`
+	Output  string `subcmd:"output,,output file for endpoints data (defaults to stdout)"`
 }
 
 type endpoints struct{}
@@ -29,13 +30,30 @@ func (endpoints) getLastKnownGoodVersions(ctx context.Context) (*Versions, error
 	return versions, nil
 }
 
+func (endpoints) getKnownGoodVersions(ctx context.Context) (*KnownGoodVersions, error) {
+	const knownGoodVersionsEndpoint = "https://googlechromelabs.github.io/chrome-for-testing/known-good-versions-with-downloads.json"
+	ep := operations.NewEndpoint[*KnownGoodVersions]()
+	versions, _, _, err := ep.Get(ctx, knownGoodVersionsEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
 type endpointsCmd struct {
 }
 
 func (e endpointsCmd) Get(ctx context.Context, f any, args []string) error {
 	fv := f.(*endpointsFlags)
-	ep := endpoints{}
-	versions, err := ep.getLastKnownGoodVersions(ctx)
+	browser, err := ParseBrowser(fv.Browser)
+	if err != nil {
+		return fmt.Errorf("invalid browser: %w", err)
+	}
+	provider, err := providerFor(browser)
+	if err != nil {
+		return err
+	}
+	versions, err := provider.ListVersions(ctx)
 	if err != nil {
 		return err
 	}
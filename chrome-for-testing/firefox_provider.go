@@ -0,0 +1,112 @@
+// Copyright 2025 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"cloudeng.io/webapi/operations"
+)
+
+// firefoxVersionsManifest is the shape of Mozilla's product-details
+// firefox_versions.json feed, used only to report the currently
+// published version numbers for get-manifest; the actual download for
+// a channel is resolved via download.mozilla.org's stable product
+// aliases below, since Mozilla doesn't publish a fixed per-build URL
+// or digest the way Chrome for Testing does.
+type firefoxVersionsManifest struct {
+	LatestFirefoxVersion      string `json:"LATEST_FIREFOX_VERSION"`
+	LatestFirefoxDevelVersion string `json:"LATEST_FIREFOX_DEVEL_VERSION"`
+	FirefoxESR                string `json:"FIREFOX_ESR"`
+}
+
+type firefoxProvider struct{}
+
+func (firefoxProvider) ListVersions(ctx context.Context) (any, error) {
+	const firefoxVersionsEndpoint = "https://product-details.mozilla.org/1.0/firefox_versions.json"
+	ep := operations.NewEndpoint[*firefoxVersionsManifest]()
+	versions, _, _, err := ep.Get(ctx, firefoxVersionsEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// firefoxProduct returns the download.mozilla.org product alias for
+// channel; these aliases always redirect to the current build for
+// that channel, which is also why a SelectedDownload from this
+// provider has no pinned Version or SHA256.
+func firefoxProduct(channel Channel) (string, error) {
+	switch channel {
+	case ChannelStable:
+		return "firefox-latest-ssl", nil
+	case ChannelBeta:
+		return "firefox-beta-latest-ssl", nil
+	case ChannelDev:
+		return "firefox-devedition-latest-ssl", nil
+	case ChannelCanary:
+		return "firefox-nightly-latest-ssl", nil
+	default:
+		return "", fmt.Errorf("unsupported firefox channel %v", channel)
+	}
+}
+
+// firefoxOS returns the download.mozilla.org os query value for platform.
+func firefoxOS(platform Platform) (string, error) {
+	switch platform {
+	case PlatformLinux64, PlatformLinuxArm64:
+		return "linux64", nil
+	case PlatformMacArm64, PlatformMacX64:
+		return "osx", nil
+	case PlatformWin64:
+		return "win64", nil
+	default:
+		return "", fmt.Errorf("unsupported firefox platform %v", platform)
+	}
+}
+
+func (firefoxProvider) Resolve(_ context.Context, rd RequestedDownload) (SelectedDownload, error) {
+	if rd.Application != ApplicationChrome {
+		return SelectedDownload{}, fmt.Errorf("firefox only supports the browser itself, not %q", rd.Application)
+	}
+	product, err := firefoxProduct(rd.Channel)
+	if err != nil {
+		return SelectedDownload{}, err
+	}
+	os, err := firefoxOS(rd.Platform)
+	if err != nil {
+		return SelectedDownload{}, err
+	}
+	url := fmt.Sprintf("https://download.mozilla.org/?product=%s&os=%s&lang=en-US", product, os)
+	return SelectedDownload{
+		Browser:     BrowserFirefox,
+		Platform:    rd.Platform,
+		Channel:     rd.Channel,
+		Application: rd.Application,
+		Download: Download{
+			Platform: rd.Platform.String(),
+			URL:      url,
+		},
+	}, nil
+}
+
+func (firefoxProvider) UserDataDir(goos string) (string, error) {
+	home, err := homeDirFor(goos)
+	if err != nil {
+		return "", err
+	}
+	switch goos {
+	case "linux":
+		return filepath.Join(home, ".mozilla", "firefox"), nil
+	case "darwin":
+		return filepath.Join(home, "Library", "Application Support", "Firefox"), nil
+	case "windows":
+		return filepath.Join(home, "Mozilla", "Firefox"), nil
+	default:
+		return "", fmt.Errorf("unsupported platform %q", goos)
+	}
+}
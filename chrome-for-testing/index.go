@@ -0,0 +1,182 @@
+// Copyright 2025 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"cloudeng.io/logging/ctxlog"
+)
+
+// indexEntry records a single successful install, keyed by
+// application/channel/platform, so that a later run can tell whether
+// the version it needs is already installed without re-downloading it.
+type indexEntry struct {
+	Application string    `json:"application"`
+	Channel     string    `json:"channel"`
+	Platform    string    `json:"platform"`
+	Version     string    `json:"version"`
+	Revision    string    `json:"revision"`
+	InstallDir  string    `json:"install_dir"`
+	Binary      string    `json:"binary"`
+	SHA256      string    `json:"sha256"`
+	InstalledAt time.Time `json:"installed_at"`
+}
+
+func (e indexEntry) matches(sd SelectedDownload) bool {
+	return e.Application == sd.Application.String() &&
+		e.Channel == sd.Channel.String() &&
+		e.Platform == sd.Platform.String() &&
+		e.Version == sd.Version
+}
+
+// toolIndex is the on-disk record of every install tracked under a
+// tool cache, persisted as JSON at "<cacheDir>/setup-chrome/index.json".
+type toolIndex struct {
+	Entries []indexEntry `json:"entries"`
+}
+
+func (tc toolCache) indexPath() string {
+	return filepath.Join(tc.cacheDir, "setup-chrome", "index.json")
+}
+
+func (tc toolCache) loadIndex() (*toolIndex, error) {
+	data, err := os.ReadFile(tc.indexPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &toolIndex{}, nil
+		}
+		return nil, fmt.Errorf("reading %q: %w", tc.indexPath(), err)
+	}
+	var idx toolIndex
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", tc.indexPath(), err)
+	}
+	return &idx, nil
+}
+
+func (tc toolCache) saveIndex(idx *toolIndex) error {
+	path := tc.indexPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("creating %q: %w", filepath.Dir(path), err)
+	}
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("writing %q: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("renaming %q to %q: %w", tmp, path, err)
+	}
+	return nil
+}
+
+// Resolve consults the installed-tool index for an entry matching sd
+// whose binary is still present on disk, short-circuiting a
+// re-download when the requested channel already resolves to the same
+// version that's already installed.
+func (tc toolCache) Resolve(sd SelectedDownload) (cachedPath string, hit bool) {
+	idx, err := tc.loadIndex()
+	if err != nil {
+		return "", false
+	}
+	for _, e := range idx.Entries {
+		if e.matches(sd) && tc.binaryExists(e.Binary) {
+			return e.Binary, true
+		}
+	}
+	return "", false
+}
+
+// recordInstall records a successful install of sd in the index,
+// replacing any prior entry for the same application/channel/platform.
+func (tc toolCache) recordInstall(sd SelectedDownload, installDir, binary, sha256 string) error {
+	idx, err := tc.loadIndex()
+	if err != nil {
+		return err
+	}
+	entry := indexEntry{
+		Application: sd.Application.String(),
+		Channel:     sd.Channel.String(),
+		Platform:    sd.Platform.String(),
+		Version:     sd.Version,
+		Revision:    sd.Revision,
+		InstallDir:  installDir,
+		Binary:      binary,
+		SHA256:      sha256,
+		InstalledAt: time.Now(),
+	}
+	filtered := idx.Entries[:0]
+	for _, e := range idx.Entries {
+		if e.Application == entry.Application && e.Channel == entry.Channel && e.Platform == entry.Platform {
+			continue
+		}
+		filtered = append(filtered, e)
+	}
+	idx.Entries = append(filtered, entry)
+	return tc.saveIndex(idx)
+}
+
+// prune keeps the keep most-recently installed versions for each
+// application/channel/platform recorded in the index and removes the
+// install directories (and index entries) for the rest. recordInstall
+// keeps only the latest entry for each application/channel/platform, and
+// every version of one is installed into the same directory, so the
+// index never holds more than one version per group: prune removes
+// nothing when keep is 1 or more, and removes the current install when
+// keep is 0.
+func (tc toolCache) prune(ctx context.Context, keep int) error {
+	logger := ctxlog.Logger(ctx)
+	idx, err := tc.loadIndex()
+	if err != nil {
+		return err
+	}
+	groups := map[string][]indexEntry{}
+	for _, e := range idx.Entries {
+		key := e.Application + "/" + e.Channel + "/" + e.Platform
+		groups[key] = append(groups[key], e)
+	}
+	var kept []indexEntry
+	for _, entries := range groups {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].InstalledAt.After(entries[j].InstalledAt) })
+		for i, e := range entries {
+			if i < keep {
+				kept = append(kept, e)
+				continue
+			}
+			logger.Info("pruning install", "application", e.Application, "channel", e.Channel, "platform", e.Platform, "version", e.Version, "install_dir", e.InstallDir)
+			if err := os.RemoveAll(e.InstallDir); err != nil {
+				return fmt.Errorf("removing %q: %w", e.InstallDir, err)
+			}
+		}
+	}
+	idx.Entries = kept
+	return tc.saveIndex(idx)
+}
+
+type pruneFlags struct {
+	CacheFlags
+	Keep int `subcmd:"keep,2,number of most-recent versions to retain per application/channel/platform"`
+}
+
+type pruneCmd struct{}
+
+func (pruneCmd) Get(ctx context.Context, f any, _ []string) error {
+	fv := f.(*pruneFlags)
+	cache, err := newToolCache(&fv.CacheFlags)
+	if err != nil {
+		return fmt.Errorf("creating tool cache: %w", err)
+	}
+	return cache.prune(ctx, fv.Keep)
+}
@@ -15,33 +15,43 @@ import (
 	"strings"
 	"time"
 
+	"cloudeng.io/citools/chrome-for-testing/cdp"
 	"cloudeng.io/logging/ctxlog"
 	"cloudeng.io/os/executil"
 )
 
-// "--user-data-dir=$USERDATA_DIR", "about:blank"
+const defaultDebuggingPort = 9222
 
 var initArgs = []string{
 	"--headless=new",
 	"--disable-gpu",
 	"--no-sandbox",
-	"--remote-debugging-port=9222",
 	"--no-default-browser-check",
 }
 
 type browser struct {
-	goos        string
-	binaryPath  string
-	userDataDir string
-	debug       bool
+	goos          string
+	binaryPath    string
+	userDataDir   string
+	debuggingPort int
+	debug         bool
 }
 
-func (b browser) init(ctx context.Context, timeout time.Duration) error {
-	stderr, stdout := &bytes.Buffer{}, &bytes.Buffer{}
-	cmd := exec.CommandContext(ctx, b.binaryPath, append(initArgs,
+// launch starts the browser with the standard initArgs plus args, returning
+// as soon as the process has started; it does not wait for the browser to
+// finish starting up.
+func (b browser) launch(ctx context.Context, args ...string) (*exec.Cmd, error) {
+	port := b.debuggingPort
+	if port == 0 {
+		port = defaultDebuggingPort
+	}
+	cmdArgs := append(append([]string{}, initArgs...),
+		fmt.Sprintf("--remote-debugging-port=%d", port),
 		"--user-data-dir="+b.userDataDir,
-		"about:blank",
-	)...)
+	)
+	cmdArgs = append(cmdArgs, args...)
+	stderr, stdout := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, b.binaryPath, cmdArgs...)
 	if b.debug {
 		cmd.Stderr = io.MultiWriter(stderr, os.Stderr)
 		cmd.Stdout = io.MultiWriter(stdout, os.Stdout)
@@ -50,22 +60,32 @@ func (b browser) init(ctx context.Context, timeout time.Duration) error {
 		cmd.Stderr = os.Stderr
 	}
 	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start command: %v: %w", strings.Join(cmd.Args, " "), err)
+		return nil, fmt.Errorf("failed to start command: %v: %w", strings.Join(cmd.Args, " "), err)
 	}
-	profileDir := filepath.Join(b.userDataDir, "Default")
-	if b.waitForProfile(ctx, profileDir, timeout) {
-		ctxlog.Info(ctx, "browser profile initialized", "profile_dir", profileDir)
-		return nil
-	}
-	pid := cmd.Process.Pid
-	ctxlog.Info(ctx, "terminating browser process after profile init timeout", "pid", pid, "profile_dir", profileDir, "timeout", timeout.String())
-	err := executil.SignalAndWait(ctx, time.Second, cmd, os.Interrupt, os.Kill)
+	return cmd, nil
+}
+
+// init launches the browser and confirms it came up by connecting to its
+// CDP endpoint, rather than polling for its profile directory to appear.
+func (b browser) init(ctx context.Context, timeout time.Duration) error {
+	cmd, err := b.launch(ctx, "about:blank")
 	if err != nil {
-		ctxlog.Info(ctx, "failed to terminate browser process", "command", strings.Join(cmd.Args, " "), "error", err)
+		return err
 	}
-	if !executil.IsStopped(pid) {
-		ctxlog.Info(ctx, "browser process still running after termination attempt", "pid", pid)
+	port := b.debuggingPort
+	if port == 0 {
+		port = defaultDebuggingPort
+	}
+	client, err := dialWithRetry(ctx, port, timeout)
+	if err == nil {
+		client.Close()
+		ctxlog.Info(ctx, "browser profile initialized", "user_data_dir", b.userDataDir, "debugging_port", port)
+		return nil
 	}
+	pid := cmd.Process.Pid
+	ctxlog.Info(ctx, "terminating browser process after profile init timeout", "pid", pid, "error", err, "timeout", timeout.String())
+	terminateProcess(ctx, cmd)
+	profileDir := filepath.Join(b.userDataDir, "Default")
 	lockFile := filepath.Join(profileDir, "SingletonLock")
 	ctxlog.Info(ctx, "waiting for browser lock file removal", "lock_file", lockFile)
 	if !b.waitForLockFileRemoval(ctx, lockFile, timeout) {
@@ -74,30 +94,39 @@ func (b browser) init(ctx context.Context, timeout time.Duration) error {
 	return nil
 }
 
-func (b browser) waitForProfile(ctx context.Context, profileDir string, timeout time.Duration) bool {
-	ticker := time.NewTicker(1 * time.Second)
+// terminateProcess signals cmd to stop and waits for it to do so, logging
+// rather than failing if it doesn't; callers that need to know whether the
+// process actually stopped should check executil.IsStopped themselves.
+func terminateProcess(ctx context.Context, cmd *exec.Cmd) {
+	pid := cmd.Process.Pid
+	if err := executil.SignalAndWait(ctx, time.Second, cmd, os.Interrupt, os.Kill); err != nil {
+		ctxlog.Info(ctx, "failed to terminate browser process", "command", strings.Join(cmd.Args, " "), "error", err)
+	}
+	if !executil.IsStopped(pid) {
+		ctxlog.Info(ctx, "browser process still running after termination attempt", "pid", pid)
+	}
+}
+
+// dialWithRetry polls cdp.Dial until it succeeds or timeout elapses, since
+// there's no signal other than the debugging port coming up for how long a
+// freshly launched browser takes to become ready.
+func dialWithRetry(ctx context.Context, port int, timeout time.Duration) (*cdp.Client, error) {
+	ticker := time.NewTicker(250 * time.Millisecond)
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 	defer ticker.Stop()
+	var lastErr error
 	for {
 		select {
 		case <-ctx.Done():
-			ctxlog.Info(ctx, "timed out waiting for profile dir", "profile_dir", profileDir, "after", timeout.String(), "error", ctx.Err())
-			return false
+			return nil, fmt.Errorf("timed out after %v waiting for CDP endpoint on port %d: %w", timeout, port, lastErr)
 		case <-ticker.C:
-			fi, err := os.Stat(profileDir)
+			client, err := cdp.Dial(ctx, port)
 			if err == nil {
-				if !fi.IsDir() {
-					ctxlog.Info(ctx, "profile dir is not a directory", "profile_dir", profileDir)
-					return false
-				}
-				return true
-			}
-			if !os.IsNotExist(err) {
-				ctxlog.Info(ctx, "error checking for profile dir", "profile_dir", profileDir, "error", err)
-				continue
+				return client, nil
 			}
-			ctxlog.Debug(ctx, "waiting for profile dir", "profile_dir", profileDir, "error", err)
+			lastErr = err
+			ctxlog.Debug(ctx, "waiting for CDP endpoint", "port", port, "error", err)
 		}
 	}
 }
@@ -0,0 +1,179 @@
+// Copyright 2025 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// lockEntry pins the exact download previously resolved for a given
+// browser/application/channel/platform, so that a later install can
+// reproduce it byte-for-byte without re-querying the browser's manifest.
+type lockEntry struct {
+	Browser     string `yaml:"browser"`
+	Application string `yaml:"application"`
+	Channel     string `yaml:"channel"`
+	Platform    string `yaml:"platform"`
+	Version     string `yaml:"version"`
+	Revision    string `yaml:"revision"`
+	URL         string `yaml:"url"`
+	SHA256      string `yaml:"sha256"`
+}
+
+func (e lockEntry) key() (browser, application, channel, platform string) {
+	if e.Browser == "" {
+		return BrowserChrome.String(), e.Application, e.Channel, e.Platform
+	}
+	return e.Browser, e.Application, e.Channel, e.Platform
+}
+
+func (e lockEntry) toSelectedDownload() SelectedDownload {
+	browser, _ := ParseBrowser(e.Browser)
+	application, _ := ParseApplication(e.Application)
+	channel, _ := ParseChannel(e.Channel)
+	platform, _ := ParsePlatform(e.Platform)
+	return SelectedDownload{
+		Browser:     browser,
+		Platform:    platform,
+		Channel:     channel,
+		Application: application,
+		Version:     e.Version,
+		Revision:    e.Revision,
+		Download: Download{
+			Platform: e.Platform,
+			URL:      e.URL,
+			SHA256:   e.SHA256,
+		},
+	}
+}
+
+func lockEntryFor(sd SelectedDownload) lockEntry {
+	return lockEntry{
+		Browser:     sd.Browser.String(),
+		Application: sd.Application.String(),
+		Channel:     sd.Channel.String(),
+		Platform:    sd.Platform.String(),
+		Version:     sd.Version,
+		Revision:    sd.Revision,
+		URL:         sd.Download.URL,
+		SHA256:      sd.Download.SHA256,
+	}
+}
+
+// lockFile is the on-disk, version-controlled representation of
+// chrome-lock.yaml: a set of pinned downloads, one per
+// browser/application/channel/platform combination, that install
+// consults instead of re-resolving the browser provider's manifest.
+type lockFile struct {
+	Entries []lockEntry `yaml:"entries"`
+}
+
+func lockFilePath() string {
+	return filepath.Join(cwd, "chrome-lock.yaml")
+}
+
+func loadLockFile() (*lockFile, error) {
+	data, err := os.ReadFile(lockFilePath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &lockFile{}, nil
+		}
+		return nil, fmt.Errorf("reading %q: %w", lockFilePath(), err)
+	}
+	var lf lockFile
+	if err := yaml.Unmarshal(data, &lf); err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", lockFilePath(), err)
+	}
+	return &lf, nil
+}
+
+func (lf *lockFile) save() error {
+	data, err := yaml.Marshal(lf)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(lockFilePath(), data, 0600); err != nil {
+		return fmt.Errorf("writing %q: %w", lockFilePath(), err)
+	}
+	return nil
+}
+
+func (lf *lockFile) find(browser, application, channel, platform string) (lockEntry, bool) {
+	for _, e := range lf.Entries {
+		b, a, c, p := e.key()
+		if b == browser && a == application && c == channel && p == platform {
+			return e, true
+		}
+	}
+	return lockEntry{}, false
+}
+
+// upsert replaces the entry matching entry's
+// browser/application/channel/platform, if any, or appends it otherwise.
+func (lf *lockFile) upsert(entry lockEntry) {
+	b, a, c, p := entry.key()
+	for i, e := range lf.Entries {
+		if eb, ea, ec, ep := e.key(); eb == b && ea == a && ec == c && ep == p {
+			lf.Entries[i] = entry
+			return
+		}
+	}
+	lf.Entries = append(lf.Entries, entry)
+}
+
+type lockFlags struct {
+	VersionFlags
+	Update bool `subcmd:"update,false,re-resolve and overwrite the lock entry even if one already exists"`
+}
+
+type lockCmd struct{}
+
+// Get resolves the download for the requested browser/application/
+// channel/platform through its BrowserProvider and pins it in
+// chrome-lock.yaml, unless an entry already exists and --update wasn't
+// given.
+func (lockCmd) Get(ctx context.Context, f any, _ []string) error {
+	fv := f.(*lockFlags)
+	browser, err := fv.VersionFlags.ParseBrowser()
+	if err != nil {
+		return fmt.Errorf("invalid browser: %w", err)
+	}
+	rd, err := fv.VersionFlags.ParseRequestedDownload()
+	if err != nil {
+		return fmt.Errorf("invalid requested download: %w", err)
+	}
+
+	lf, err := loadLockFile()
+	if err != nil {
+		return err
+	}
+
+	if existing, ok := lf.find(browser.String(), rd.Application.String(), rd.Channel.String(), rd.Platform.String()); ok && !fv.Update {
+		fmt.Printf("%v/%v/%v/%v already locked to version %v, use --update to refresh it\n",
+			existing.Browser, existing.Application, existing.Channel, existing.Platform, existing.Version)
+		return nil
+	}
+
+	provider, err := providerFor(browser)
+	if err != nil {
+		return err
+	}
+	sd, err := provider.Resolve(ctx, rd)
+	if err != nil {
+		return fmt.Errorf("getting selected download: %w", err)
+	}
+
+	lf.upsert(lockEntryFor(sd))
+	if err := lf.save(); err != nil {
+		return err
+	}
+	fmt.Printf("locked %v/%v/%v/%v to version %v (%v)\n", browser, sd.Application, sd.Channel, sd.Platform, sd.Version, sd.Download.SHA256)
+	return nil
+}
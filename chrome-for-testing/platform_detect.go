@@ -0,0 +1,33 @@
+// Copyright 2025 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// DetectPlatform returns the Platform matching the current host. On
+// Apple Silicon it honors Rosetta 2 translation, so a process built
+// for x86_64 and running under emulation selects mac-x64 rather than
+// mac-arm64.
+func DetectPlatform() (Platform, error) {
+	switch runtime.GOOS {
+	case "linux":
+		if runtime.GOARCH == "arm64" {
+			return PlatformLinuxArm64, nil
+		}
+		return PlatformLinux64, nil
+	case "windows":
+		return PlatformWin64, nil
+	case "darwin":
+		if runtime.GOARCH == "arm64" && !isRosettaTranslated() {
+			return PlatformMacArm64, nil
+		}
+		return PlatformMacX64, nil
+	default:
+		return 0, fmt.Errorf("unsupported platform: %v/%v", runtime.GOOS, runtime.GOARCH)
+	}
+}
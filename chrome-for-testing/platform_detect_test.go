@@ -0,0 +1,66 @@
+// Copyright 2025 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestParsePlatform(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want Platform
+	}{
+		{"linux64", PlatformLinux64},
+		{"mac-arm64", PlatformMacArm64},
+		{"mac-x64", PlatformMacX64},
+		{"win64", PlatformWin64},
+		{"linux-arm64", PlatformLinuxArm64},
+	} {
+		got, err := ParsePlatform(tc.s)
+		if err != nil {
+			t.Errorf("ParsePlatform(%q): unexpected error: %v", tc.s, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParsePlatform(%q) = %v, want %v", tc.s, got, tc.want)
+		}
+		if got.String() != tc.s {
+			t.Errorf("%v.String() = %q, want %q", got, got.String(), tc.s)
+		}
+	}
+}
+
+func TestParsePlatformUnknown(t *testing.T) {
+	if _, err := ParsePlatform("bogus"); err == nil {
+		t.Fatal("ParsePlatform(\"bogus\"): expected an error, got nil")
+	}
+}
+
+func TestDetectPlatform(t *testing.T) {
+	got, err := DetectPlatform()
+	if err != nil {
+		t.Fatalf("DetectPlatform: unexpected error: %v", err)
+	}
+	switch runtime.GOOS {
+	case "linux":
+		if runtime.GOARCH == "arm64" {
+			if got != PlatformLinuxArm64 {
+				t.Errorf("DetectPlatform() = %v, want %v", got, PlatformLinuxArm64)
+			}
+		} else if got != PlatformLinux64 {
+			t.Errorf("DetectPlatform() = %v, want %v", got, PlatformLinux64)
+		}
+	case "windows":
+		if got != PlatformWin64 {
+			t.Errorf("DetectPlatform() = %v, want %v", got, PlatformWin64)
+		}
+	case "darwin":
+		if got != PlatformMacArm64 && got != PlatformMacX64 {
+			t.Errorf("DetectPlatform() = %v, want PlatformMacArm64 or PlatformMacX64", got)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright 2025 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// homeDirFor returns the directory that a browser provider's
+// UserDataDir should build its profile path under for goos: HOME on
+// linux/darwin, LOCALAPPDATA on windows. Shared across providers so
+// each one only has to know the browser-specific path components.
+func homeDirFor(goos string) (string, error) {
+	switch goos {
+	case "linux", "darwin":
+		home := os.Getenv("HOME")
+		if home == "" {
+			return "", fmt.Errorf("HOME environment variable not set")
+		}
+		return home, nil
+	case "windows":
+		localAppData := os.Getenv("LOCALAPPDATA")
+		if localAppData == "" {
+			return "", fmt.Errorf("LOCALAPPDATA environment variable not set")
+		}
+		return localAppData, nil
+	default:
+		return "", fmt.Errorf("unsupported platform %q", goos)
+	}
+}
@@ -0,0 +1,39 @@
+// Copyright 2025 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// BrowserProvider is implemented once per browser family (Chrome for
+// Testing, Firefox, Edge for Testing, ...) so that install,
+// user-data-dir and get-manifest can all route through the same
+// caching, extraction and GitHub Actions output plumbing regardless of
+// which browser was requested via --browser.
+type BrowserProvider interface {
+	// ListVersions returns the provider's release manifest, in
+	// whatever shape it is published in, for get-manifest to dump.
+	ListVersions(ctx context.Context) (any, error)
+	// Resolve returns the SelectedDownload matching rd.
+	Resolve(ctx context.Context, rd RequestedDownload) (SelectedDownload, error)
+	// UserDataDir returns the default profile directory for goos.
+	UserDataDir(goos string) (string, error)
+}
+
+// providerFor returns the BrowserProvider registered for browser.
+func providerFor(browser Browser) (BrowserProvider, error) {
+	switch browser {
+	case BrowserChrome:
+		return chromeProvider{}, nil
+	case BrowserFirefox:
+		return firefoxProvider{}, nil
+	case BrowserEdge:
+		return edgeProvider{}, nil
+	default:
+		return nil, fmt.Errorf("no provider registered for browser %v", browser)
+	}
+}
@@ -0,0 +1,353 @@
+// Copyright 2025 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cloudeng.io/logging/ctxlog"
+)
+
+// byteRange is a half-open [Start, End) span of a download.
+type byteRange struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+func (r byteRange) len() int64 { return r.End - r.Start }
+
+// rangeState records the byte ranges of a download already written to
+// disk, persisted next to the download as "<path>.part.json" so that a
+// later run can resume it rather than restart from scratch. It is
+// keyed by URL and expected size as a cheap guard against resuming a
+// download for a different (e.g. re-released) artifact that happens to
+// land on the same cache path.
+type rangeState struct {
+	URL       string      `json:"url"`
+	Size      int64       `json:"size"`
+	Completed []byteRange `json:"completed"`
+}
+
+func sidecarPath(downloadPath string) string {
+	return downloadPath + ".part.json"
+}
+
+func loadRangeState(downloadPath, url string, size int64) *rangeState {
+	data, err := os.ReadFile(sidecarPath(downloadPath))
+	if err != nil {
+		return &rangeState{URL: url, Size: size}
+	}
+	var st rangeState
+	if err := json.Unmarshal(data, &st); err != nil || st.URL != url || st.Size != size {
+		return &rangeState{URL: url, Size: size}
+	}
+	return &st
+}
+
+func (st *rangeState) save(downloadPath string) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(sidecarPath(downloadPath), data, 0600)
+}
+
+// remaining returns the sub-ranges of [0, size) not yet covered by
+// st.Completed.
+func (st *rangeState) remaining(size int64) []byteRange {
+	completed := append([]byteRange(nil), st.Completed...)
+	sort.Slice(completed, func(i, j int) bool { return completed[i].Start < completed[j].Start })
+	merged := completed[:0]
+	for _, r := range completed {
+		if len(merged) > 0 && r.Start <= merged[len(merged)-1].End {
+			if r.End > merged[len(merged)-1].End {
+				merged[len(merged)-1].End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	var gaps []byteRange
+	pos := int64(0)
+	for _, r := range merged {
+		if r.Start > pos {
+			gaps = append(gaps, byteRange{pos, r.Start})
+		}
+		pos = r.End
+	}
+	if pos < size {
+		gaps = append(gaps, byteRange{pos, size})
+	}
+	return gaps
+}
+
+// splitRanges splits each gap into up to n roughly equal pieces so
+// that, in aggregate, around n workers stay busy.
+func splitRanges(gaps []byteRange, n int) []byteRange {
+	if n <= 1 {
+		return gaps
+	}
+	var out []byteRange
+	for _, g := range gaps {
+		length := g.len()
+		if length <= 0 {
+			continue
+		}
+		pieces := int64(n)
+		if pieces > length {
+			pieces = length
+		}
+		chunkSize := (length + pieces - 1) / pieces
+		if chunkSize == 0 {
+			chunkSize = length
+		}
+		for start := g.Start; start < g.End; start += chunkSize {
+			end := start + chunkSize
+			if end > g.End {
+				end = g.End
+			}
+			out = append(out, byteRange{start, end})
+		}
+	}
+	return out
+}
+
+// rangedDownloader downloads a URL to a local file using concurrent
+// HTTP byte-range requests, retrying individual ranges with jittered
+// exponential backoff, and resuming a prior partial download recorded
+// in a ".part.json" sidecar when resume is enabled. It writes to a
+// "<dst>.tmp" file throughout and only renames it to dst once every
+// range has landed successfully, so a dst path never observes a
+// partially written file.
+type rangedDownloader struct {
+	client    *http.Client
+	parallel  int
+	retries   int
+	resume    bool
+	retryBase time.Duration
+	retryMax  time.Duration
+}
+
+func newRangedDownloader(parallel, retries int, resume bool, retryBase, retryMax time.Duration) *rangedDownloader {
+	if parallel < 1 {
+		parallel = 1
+	}
+	if retries < 0 {
+		retries = 0
+	}
+	if retryBase <= 0 {
+		retryBase = time.Second
+	}
+	if retryMax <= 0 {
+		retryMax = 30 * time.Second
+	}
+	return &rangedDownloader{client: http.DefaultClient, parallel: parallel, retries: retries, resume: resume, retryBase: retryBase, retryMax: retryMax}
+}
+
+// download fetches url into dst and returns the size in bytes of the
+// downloaded file. If the server does not advertise byte-range support, or the
+// content length is unknown, it falls back to a single, whole-file GET.
+// On success dst.tmp is renamed to dst; on failure it is left in place
+// (alongside its sidecar) so the next call can resume it.
+func (d *rangedDownloader) download(ctx context.Context, url, dst string) (int64, error) {
+	logger := ctxlog.Logger(ctx)
+	tmp := dst + ".tmp"
+
+	size, acceptsRanges, err := d.head(ctx, url)
+	if err != nil {
+		return 0, fmt.Errorf("HEAD %q: %w", url, err)
+	}
+
+	var st *rangeState
+	if d.resume {
+		st = loadRangeState(tmp, url, size)
+	} else {
+		st = &rangeState{URL: url, Size: size}
+	}
+
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("creating %q: %w", tmp, err)
+	}
+	if size > 0 {
+		if err := f.Truncate(size); err != nil {
+			f.Close()
+			return 0, fmt.Errorf("truncating %q to %d bytes: %w", tmp, size, err)
+		}
+	}
+
+	ranged := acceptsRanges && size > 0
+	var gaps []byteRange
+	if ranged {
+		gaps = st.remaining(size)
+	} else {
+		gaps = []byteRange{{0, size}}
+	}
+	if len(gaps) > 0 {
+		chunks := gaps
+		if ranged {
+			chunks = splitRanges(gaps, d.parallel)
+		}
+
+		var (
+			mu       sync.Mutex
+			firstErr error
+			written  int64
+		)
+		done := make(chan struct{})
+		start := time.Now()
+		go logDownloadProgress(ctx, &mu, &written, size, start, done)
+
+		sem := make(chan struct{}, d.parallel)
+		var wg sync.WaitGroup
+		for _, rg := range chunks {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(rg byteRange) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				_, err := d.fetchRangeWithRetry(ctx, url, f, rg, ranged, &mu, &written)
+				mu.Lock()
+				defer mu.Unlock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+					return
+				}
+				st.Completed = append(st.Completed, rg)
+				if d.resume {
+					_ = st.save(tmp)
+				}
+			}(rg)
+		}
+		wg.Wait()
+		close(done)
+
+		if firstErr != nil {
+			f.Close()
+			return written, firstErr
+		}
+	} else {
+		logger.Info("download already complete", "url", url, "path", tmp)
+	}
+
+	if err := f.Close(); err != nil {
+		return size, fmt.Errorf("closing %q: %w", tmp, err)
+	}
+	if size < 0 {
+		if fi, err := os.Stat(tmp); err == nil {
+			size = fi.Size()
+		}
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		return size, fmt.Errorf("renaming %q to %q: %w", tmp, dst, err)
+	}
+	os.Remove(sidecarPath(tmp))
+	return size, nil
+}
+
+func (d *rangedDownloader) head(ctx context.Context, url string) (size int64, acceptsRanges bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, false, fmt.Errorf("unexpected status %v", resp.Status)
+	}
+	return resp.ContentLength, strings.EqualFold(resp.Header.Get("Accept-Ranges"), "bytes"), nil
+}
+
+func (d *rangedDownloader) fetchRangeWithRetry(ctx context.Context, url string, f *os.File, rg byteRange, ranged bool, mu *sync.Mutex, written *int64) (int64, error) {
+	var lastErr error
+	var total int64
+	for attempt := 0; attempt <= d.retries; attempt++ {
+		if attempt > 0 {
+			backoff := d.backoff(attempt)
+			mu.Lock()
+			bytesSoFar := *written
+			mu.Unlock()
+			ctxlog.Info(ctx, "retrying download range", "url", url, "start", rg.Start, "end", rg.End,
+				"attempt", attempt, "backoff", backoff, "bytes_so_far", bytesSoFar)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return total, ctx.Err()
+			}
+		}
+		n, err := d.fetchRange(ctx, url, f, rg, ranged)
+		mu.Lock()
+		*written += n
+		mu.Unlock()
+		total += n
+		if err == nil {
+			return total, nil
+		}
+		lastErr = err
+	}
+	return total, fmt.Errorf("range %d-%d: %w", rg.Start, rg.End, lastErr)
+}
+
+func (d *rangedDownloader) fetchRange(ctx context.Context, url string, f *os.File, rg byteRange, ranged bool) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	if ranged {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", rg.Start, rg.End-1))
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %v for range %d-%d", resp.Status, rg.Start, rg.End)
+	}
+	return io.Copy(io.NewOffsetWriter(f, rg.Start), resp.Body)
+}
+
+// backoff returns an exponential backoff based on d.retryBase, capped
+// at d.retryMax, with jitter that shortens it by up to 50%.
+func (d *rangedDownloader) backoff(attempt int) time.Duration {
+	delay := d.retryBase << uint(attempt-1)
+	if delay > d.retryMax || delay <= 0 {
+		delay = d.retryMax
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func logDownloadProgress(ctx context.Context, mu *sync.Mutex, written *int64, total int64, start time.Time, done <-chan struct{}) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	logger := ctxlog.Logger(ctx)
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			mu.Lock()
+			w := *written
+			mu.Unlock()
+			speed := float64(w) / 1024 / 1024 / time.Since(start).Seconds()
+			logger.Info("download progress", "bytes", w, "total", total, "speed_MBps", speed)
+		}
+	}
+}
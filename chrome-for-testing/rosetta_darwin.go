@@ -0,0 +1,17 @@
+// Copyright 2025 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+//go:build darwin
+
+package main
+
+import "golang.org/x/sys/unix"
+
+// isRosettaTranslated reports whether the current process is running
+// under Rosetta 2 binary translation, which happens when an x86_64
+// build runs on Apple Silicon.
+func isRosettaTranslated() bool {
+	v, err := unix.SysctlUint32("sysctl.proc_translated")
+	return err == nil && v == 1
+}
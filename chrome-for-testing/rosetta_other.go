@@ -0,0 +1,11 @@
+// Copyright 2025 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+//go:build !darwin
+
+package main
+
+// isRosettaTranslated is always false outside of darwin; Rosetta 2
+// translation only applies to macOS on Apple Silicon.
+func isRosettaTranslated() bool { return false }
@@ -0,0 +1,153 @@
+// Copyright 2025 cloudeng llc. All rights reserved.
+// Use of this source code is governed by the Apache-2.0
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"time"
+
+	"cloudeng.io/citools/chrome-for-testing/cdp"
+	"cloudeng.io/logging/ctxlog"
+)
+
+type runFlags struct {
+	VersionFlags
+	CacheFlags
+	DebuggingPort int           `subcmd:"debugging-port,9222,remote debugging port to launch the browser on"`
+	URL           string        `subcmd:"url,about:blank,url to navigate to once the browser has started"`
+	Timeout       time.Duration `subcmd:"timeout,30s,'how long to wait for the browser to start and for the page to finish loading'"`
+	Debug         bool          `subcmd:"debug,false,enable debug output"`
+}
+
+type runCmd struct{}
+
+// Get launches the already-installed browser matching f's version flags,
+// navigates to --url and waits for it to finish loading, and then shuts the
+// browser down cleanly via the CDP Browser.close method rather than
+// signalling the process.
+func (runCmd) Get(ctx context.Context, f any, _ []string) error {
+	fv := f.(*runFlags)
+	requestedBrowser, err := fv.VersionFlags.ParseBrowser()
+	if err != nil {
+		return fmt.Errorf("invalid browser: %w", err)
+	}
+	provider, err := providerFor(requestedBrowser)
+	if err != nil {
+		return err
+	}
+	cache, err := newToolCache(&fv.CacheFlags)
+	if err != nil {
+		return fmt.Errorf("creating tool cache: %w", err)
+	}
+	sd, _, err := (downloadInstallCmd{}).getSelectedDownload(ctx, provider, requestedBrowser, fv.VersionFlags)
+	if err != nil {
+		return fmt.Errorf("resolving version: %w", err)
+	}
+	_, binaryPath, _, err := cache.applicationPaths(sd)
+	if err != nil {
+		return fmt.Errorf("getting application paths: %w", err)
+	}
+	if _, hit := cache.Resolve(sd); !hit {
+		return fmt.Errorf("%v/%v/%v/%v is not installed, run 'install' first", requestedBrowser, sd.Application, sd.Channel, sd.Platform)
+	}
+	userDataDir, err := provider.UserDataDir(runtime.GOOS)
+	if err != nil {
+		return fmt.Errorf("determining user data dir: %w", err)
+	}
+
+	b := browser{
+		goos:          runtime.GOOS,
+		binaryPath:    binaryPath,
+		userDataDir:   userDataDir,
+		debuggingPort: fv.DebuggingPort,
+		debug:         fv.Debug,
+	}
+	cmd, err := b.launch(ctx, "about:blank")
+	if err != nil {
+		return fmt.Errorf("launching browser: %w", err)
+	}
+
+	client, err := dialWithRetry(ctx, fv.DebuggingPort, fv.Timeout)
+	if err != nil {
+		terminateProcess(ctx, cmd)
+		return fmt.Errorf("connecting to browser: %w", err)
+	}
+	defer client.Close()
+
+	sessionID, err := navigateAndWaitForLoad(ctx, client, fv.URL, fv.Timeout)
+	if err != nil {
+		_, _ = client.Send(ctx, "Browser.close", nil)
+		return fmt.Errorf("navigating to %q: %w", fv.URL, err)
+	}
+	ctxlog.Info(ctx, "page loaded", "url", fv.URL)
+
+	if _, err := client.SendSession(ctx, sessionID, "Page.close", nil); err != nil {
+		ctxlog.Info(ctx, "failed to close page", "error", err)
+	}
+	if _, err := client.Send(ctx, "Browser.close", nil); err != nil {
+		return fmt.Errorf("closing browser: %w", err)
+	}
+	return cmd.Wait()
+}
+
+// navigateAndWaitForLoad attaches to the browser's first page target,
+// navigates it to url, and waits for that target's Page.loadEventFired
+// event, returning the CDP session it navigated so the caller can close it.
+func navigateAndWaitForLoad(ctx context.Context, client *cdp.Client, url string, timeout time.Duration) (string, error) {
+	raw, err := client.Send(ctx, "Target.getTargets", nil)
+	if err != nil {
+		return "", fmt.Errorf("Target.getTargets: %w", err)
+	}
+	var targets struct {
+		TargetInfos []struct {
+			TargetID string `json:"targetId"`
+			Type     string `json:"type"`
+		} `json:"targetInfos"`
+	}
+	if err := json.Unmarshal(raw, &targets); err != nil {
+		return "", fmt.Errorf("parsing Target.getTargets result: %w", err)
+	}
+	var targetID string
+	for _, t := range targets.TargetInfos {
+		if t.Type == "page" {
+			targetID = t.TargetID
+			break
+		}
+	}
+	if targetID == "" {
+		return "", fmt.Errorf("no page target found")
+	}
+
+	raw, err = client.Send(ctx, "Target.attachToTarget", map[string]any{"targetId": targetID, "flatten": true})
+	if err != nil {
+		return "", fmt.Errorf("Target.attachToTarget: %w", err)
+	}
+	var attached struct {
+		SessionID string `json:"sessionId"`
+	}
+	if err := json.Unmarshal(raw, &attached); err != nil {
+		return "", fmt.Errorf("parsing Target.attachToTarget result: %w", err)
+	}
+
+	loaded := client.Subscribe("Page.loadEventFired")
+	if _, err := client.SendSession(ctx, attached.SessionID, "Page.enable", nil); err != nil {
+		return attached.SessionID, fmt.Errorf("Page.enable: %w", err)
+	}
+	if _, err := client.SendSession(ctx, attached.SessionID, "Page.navigate", map[string]string{"url": url}); err != nil {
+		return attached.SessionID, fmt.Errorf("Page.navigate: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	select {
+	case <-loaded:
+		return attached.SessionID, nil
+	case <-ctx.Done():
+		return attached.SessionID, fmt.Errorf("timed out after %v waiting for Page.loadEventFired: %w", timeout, ctx.Err())
+	}
+}
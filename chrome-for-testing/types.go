@@ -16,6 +16,7 @@ type RequestedDownload struct {
 }
 
 type SelectedDownload struct {
+	Browser     Browser
 	Platform    Platform
 	Channel     Channel
 	Application Application
@@ -72,9 +73,107 @@ func (v Versions) GetRequestedDownload(rd RequestedDownload) (SelectedDownload,
 	}, err
 }
 
+// KnownGoodVersions is the shape of the Chrome for Testing
+// known-good-versions-with-downloads.json feed: every released version,
+// rather than just the last known good one per channel.
+type KnownGoodVersions struct {
+	Timestamp string         `json:"timestamp"`
+	Versions  []VersionEntry `json:"versions"`
+}
+
+type VersionEntry struct {
+	Version   string               `json:"version"`
+	Revision  string               `json:"revision"`
+	Downloads map[string]Downloads `json:"downloads"`
+}
+
+// SelectChromeDriverFor returns the SelectedDownload for the chromedriver
+// release that matches detectedChromeVersion by major version, preferring
+// an exact version match and otherwise falling back to the
+// nearest-lower version within that major. Channel does not apply to
+// per-version downloads selected this way, so SelectedDownload.Channel
+// is left as its zero value.
+func (k KnownGoodVersions) SelectChromeDriverFor(detectedChromeVersion string, platform Platform) (SelectedDownload, error) {
+	want, err := parseChromeVersion(detectedChromeVersion)
+	if err != nil {
+		return SelectedDownload{}, fmt.Errorf("parsing detected chrome version %q: %w", detectedChromeVersion, err)
+	}
+	var best *VersionEntry
+	var bestVersion chromeVersion
+	for i := range k.Versions {
+		ve := &k.Versions[i]
+		v, err := parseChromeVersion(ve.Version)
+		if err != nil {
+			continue
+		}
+		if v.major != want.major || v.compare(want) > 0 {
+			continue
+		}
+		if best == nil || v.compare(bestVersion) > 0 {
+			best, bestVersion = ve, v
+		}
+	}
+	if best == nil {
+		return SelectedDownload{}, fmt.Errorf("no chromedriver found matching installed chrome version %q (major %d)", detectedChromeVersion, want.major)
+	}
+	downloads, ok := best.Downloads[ApplicationChromeDriver.String()]
+	if !ok {
+		return SelectedDownload{}, fmt.Errorf("no chromedriver downloads for version %q", best.Version)
+	}
+	dl, err := downloads.GetByPlatform(platform)
+	if err != nil {
+		return SelectedDownload{}, err
+	}
+	return SelectedDownload{
+		Platform:    platform,
+		Application: ApplicationChromeDriver,
+		Download:    dl,
+		Version:     best.Version,
+		Revision:    best.Revision,
+		Prefix:      downloads.LongestCommonPrefix(),
+	}, nil
+}
+
+// chromeVersion is a parsed Chrome for Testing version number of the
+// form major.minor.build.patch.
+type chromeVersion struct {
+	major, minor, build, patch int
+}
+
+func parseChromeVersion(s string) (chromeVersion, error) {
+	var v chromeVersion
+	parts := strings.Split(strings.TrimSpace(s), ".")
+	if len(parts) != 4 {
+		return v, fmt.Errorf("malformed chrome version %q: want major.minor.build.patch", s)
+	}
+	fields := []*int{&v.major, &v.minor, &v.build, &v.patch}
+	for i, p := range parts {
+		if _, err := fmt.Sscanf(p, "%d", fields[i]); err != nil {
+			return v, fmt.Errorf("malformed chrome version %q: %w", s, err)
+		}
+	}
+	return v, nil
+}
+
+// compare returns a negative number if v < o, 0 if v == o, and a
+// positive number if v > o.
+func (v chromeVersion) compare(o chromeVersion) int {
+	for _, pair := range [][2]int{{v.major, o.major}, {v.minor, o.minor}, {v.build, o.build}, {v.patch, o.patch}} {
+		if d := pair[0] - pair[1]; d != 0 {
+			return d
+		}
+	}
+	return 0
+}
+
 type Download struct {
 	Platform string `json:"platform"`
 	URL      string `json:"url"`
+	// SHA256 is the artifact's digest, when the provider's upstream
+	// manifest publishes one. Edge's manifest does; Chrome's and
+	// Firefox's don't, so this is empty for those browsers.
+	SHA256 string `json:"sha256,omitempty"`
+	Size   int64  `json:"size,omitempty"`
 }
 
 type Downloads []Download
@@ -145,6 +244,7 @@ const (
 	PlatformMacArm64
 	PlatformMacX64
 	PlatformWin64
+	PlatformLinuxArm64
 )
 
 func ParsePlatform(s string) (Platform, error) {
@@ -157,8 +257,10 @@ func ParsePlatform(s string) (Platform, error) {
 		return PlatformMacX64, nil
 	case "win64":
 		return PlatformWin64, nil
+	case "linux-arm64":
+		return PlatformLinuxArm64, nil
 	default:
-		return 0, fmt.Errorf("unknown platform: %q: use of linux64, mac-arm64, mac-x64, win64", s)
+		return 0, fmt.Errorf("unknown platform: %q: use one of linux64, mac-arm64, mac-x64, win64, linux-arm64", s)
 	}
 }
 
@@ -172,6 +274,8 @@ func (p Platform) String() string {
 		return "mac-x64"
 	case PlatformWin64:
 		return "win64"
+	case PlatformLinuxArm64:
+		return "linux-arm64"
 	default:
 		return "unknown"
 	}
@@ -216,6 +320,40 @@ func (c Channel) String() string {
 	}
 }
 
+type Browser int
+
+const (
+	BrowserChrome Browser = iota
+	BrowserFirefox
+	BrowserEdge
+)
+
+func ParseBrowser(s string) (Browser, error) {
+	switch s {
+	case "", "chrome":
+		return BrowserChrome, nil
+	case "firefox":
+		return BrowserFirefox, nil
+	case "edge":
+		return BrowserEdge, nil
+	default:
+		return 0, fmt.Errorf("unknown browser: %q: use one of chrome, firefox, edge", s)
+	}
+}
+
+func (b Browser) String() string {
+	switch b {
+	case BrowserChrome:
+		return "chrome"
+	case BrowserFirefox:
+		return "firefox"
+	case BrowserEdge:
+		return "edge"
+	default:
+		return "unknown"
+	}
+}
+
 type Application int
 
 const (
@@ -7,12 +7,11 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
+	"runtime"
 	"strings"
 	"time"
 
@@ -24,25 +23,40 @@ func prepareInstallDir(_ context.Context, _ string) error {
 	return nil
 }
 
-func getVersion(ctx context.Context, debug bool, binaryPath string) (string, error) {
-	args := []string{"--version"}
-	ctxlog.Debug(ctx, "running", "binary", binaryPath, "args", args)
-	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
-	cmd := exec.CommandContext(ctx, binaryPath, args...)
-	if debug {
-		cmd.Stderr = io.MultiWriter(stderr, os.Stderr)
-		cmd.Stdout = io.MultiWriter(stdout, os.Stdout)
-	} else {
-		cmd.Stderr = stderr
-		cmd.Stdout = stdout
+// detectInstalledChromeVersion probes the local machine for an
+// installed Chrome and returns its major.minor.build.patch version.
+func detectInstalledChromeVersion(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if runtime.GOOS == "darwin" {
+		return detectInstalledChromeVersionDarwin(ctx)
 	}
-	err := cmd.Run()
+	return detectInstalledChromeVersionLinux(ctx)
+}
+
+func detectInstalledChromeVersionLinux(ctx context.Context) (string, error) {
+	const binary = "google-chrome"
+	ctxlog.Debug(ctx, "running", "binary", binary, "args", []string{"--version"})
+	out, err := exec.CommandContext(ctx, binary, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("running %v --version: %w", binary, err)
+	}
+	// output is of the form "Google Chrome 123.0.6312.58 "
+	fields := strings.Fields(string(out))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("unexpected output from %v --version: %q", binary, out)
+	}
+	return fields[len(fields)-1], nil
+}
+
+func detectInstalledChromeVersionDarwin(ctx context.Context) (string, error) {
+	const appPath = "/Applications/Google Chrome.app"
+	ctxlog.Debug(ctx, "running", "binary", "mdls", "path", appPath)
+	out, err := exec.CommandContext(ctx, "mdls", "-name", "kMDItemVersion", "-raw", appPath).Output()
 	if err != nil {
-		ctxlog.Debug(ctx, "command stdout", "stdout", stdout.String())
-		ctxlog.Debug(ctx, "command stderr", "stderr", stderr.String())
-		return "", fmt.Errorf("running %v: %w", strings.Join(cmd.Args, " "), err)
+		return "", fmt.Errorf("running mdls on %q: %w", appPath, err)
 	}
-	return string(bytes.TrimSpace(stdout.Bytes())), nil
+	return strings.TrimSpace(string(out)), nil
 }
 
 func terminateChromeProcesses(ctx context.Context, cmd *exec.Cmd, binaryPath string, debug bool) error {
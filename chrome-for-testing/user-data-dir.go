@@ -13,7 +13,8 @@ import (
 )
 
 type userDataDirFlags struct {
-	OS string `subcmd:"os,,operating system for which to determine the user data dir (linux, darwin, windows). If not specified, the current OS is used."`
+	Browser string `subcmd:"browser,chrome,'browser to determine the user data dir for, one of chrome, firefox, edge'"`
+	OS      string `subcmd:"os,,operating system for which to determine the user data dir (linux, darwin, windows). If not specified, the current OS is used."`
 }
 
 func (d *downloadInstallCmd) userDataDirCmd(ctx context.Context, f any, args []string) error {
@@ -22,13 +23,20 @@ func (d *downloadInstallCmd) userDataDirCmd(ctx context.Context, f any, args []s
 	if goos == "" {
 		goos = runtime.GOOS
 	}
-	udDir, err := getUserDataDir(goos)
+	browser, err := ParseBrowser(fv.Browser)
+	if err != nil {
+		return fmt.Errorf("invalid browser: %w", err)
+	}
+	provider, err := providerFor(browser)
+	if err != nil {
+		return err
+	}
+	udDir, err := provider.UserDataDir(goos)
 	if err != nil {
 		return fmt.Errorf("determining user data dir: %w", err)
 	}
 	fmt.Println(udDir)
 	return nil
-
 }
 
 func getUserDataDir(goos string) (string, error) {
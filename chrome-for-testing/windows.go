@@ -38,18 +38,20 @@ func prepareInstallDir(ctx context.Context, dir string) error {
 	return nil
 }
 
-func getVersion(ctx context.Context, debug bool, binaryPath string) (string, error) {
+// detectInstalledChromeVersion probes the local machine for an
+// installed Chrome and returns its major.minor.build.patch version.
+func detectInstalledChromeVersion(ctx context.Context) (string, error) {
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 	pwsh := powershell.New()
-	psCommand := fmt.Sprintf(`(Get-Item "%s").VersionInfo.ProductVersion`, binaryPath)
+	const chromePath = `C:\Program Files\Google\Chrome\Application\chrome.exe`
+	psCommand := fmt.Sprintf(`(Get-Item "%s").VersionInfo.ProductVersion`, chromePath)
 	args := []string{"-NoProfile", "-Command", psCommand}
 	stdout, stderr, err := pwsh.Run(ctx, args...)
 	if err != nil {
-		logError(ctx, "failed to get version info", stdout, stderr, args, err)
-		return "", fmt.Errorf("failed to get version info for %v: %w", binaryPath, err)
+		logError(ctx, "failed to detect installed chrome version", stdout, stderr, args, err)
+		return "", fmt.Errorf("failed to detect installed chrome version: %w", err)
 	}
-	ctxlog.Info(ctx, "got version info", "binary", binaryPath, "version", strings.TrimSpace(stdout))
 	return strings.TrimSpace(stdout), nil
 }
 
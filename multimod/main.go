@@ -17,10 +17,54 @@
 // commands to be run, as in:
 //
 //	update: ["go", "get", "-u", "./...", ";", "go", "mod", "tidy"]
+//
+// Additional actions beyond the builtin ones can be declared under a
+// top-level 'tasks' list, each with a name, a cmd, an optional list of
+// other actions it depends on (which are run, across every module,
+// before it is), and an optional cache flag that, when set, skips
+// re-running the action in a module whose go.mod dependency closure
+// hasn't changed since the last successful run:
+//
+//	tasks:
+//	  - name: fuzz
+//	    cmd: ["go", "test", "-fuzz=.", "-fuzztime=30s", "./..."]
+//	    depends_on: ["generate"]
+//	    cache: true
+//
+// Within a single action, modules are run in the order imposed by their
+// go.mod require directives on each other (see runInDirs), with up to
+// -jobs (or its -j alias) of them running concurrently; -graph prints
+// that dependency graph as Graphviz instead of running anything.
+//
+// -report <dir> writes dir/summary.json, dir/junit.xml and a full
+// stdout/stderr log per module/action, for ingestion by CI test
+// reporters; a "go test" action is automatically run with -json added
+// so its JUnit output has one <testcase> per Go test function rather
+// than one per module. By default the first failure stops the run;
+// -continue-on-error keeps going so -report has complete results, and
+// -fail-fast makes the default explicit (the two are mutually
+// exclusive).
+//
+// Module discovery prefers a go.work file at the repository root, if
+// one exists, over walking the filesystem for go.mod files, so that a
+// module deliberately left out of go.work (e.g. an example that isn't
+// meant to be built as part of the workspace) is left out here too.
+// -include and -exclude take glob patterns (matched against either a
+// module's directory or its go.mod module path) and may be repeated;
+// -exclude takes precedence over -include. -changed-since <git-ref>
+// restricts the modules run to those containing a file changed since
+// ref, plus any module that locally depends on one of them, so a PR
+// build can test only what it actually touched.
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	stderrors "errors"
 	"flag"
 	"fmt"
 	"io/fs"
@@ -28,8 +72,12 @@ import (
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"runtime"
 	"slices"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"cloudeng.io/errors"
 	"golang.org/x/mod/modfile"
@@ -60,6 +108,8 @@ annotate:
 usage: ["gousage", "--overwrite", "./..."]
 update: ["go", "get", "-u", "./...", ";",
          "go", "mod", "tidy"]
+vendor: ["go", "mod", "vendor"]
+prewarm: ["go", "mod", "download", "-x"]
 `
 
 type config struct {
@@ -71,10 +121,38 @@ type config struct {
 	Usage      []string            `yaml:"usage,flow"`
 	Annotate   []string            `yaml:"annotate,flow"`
 	Update     []string            `yaml:"update,flow"`
+	Vendor     []string            `yaml:"vendor,flow"`
+	Prewarm    []string            `yaml:"prewarm,flow"`
 	Exclusions map[string][]string `yaml:"exclusions"`
+	Tasks      []taskDef           `yaml:"tasks"`
+}
+
+// taskDef declares an action beyond the fixed test/lint/govuln trio (and
+// the rest of the builtin config fields above): Name is the action name
+// as passed on the command line, Cmd is run exactly like a builtin
+// action's command, DependsOn names other actions that must complete
+// (across every module) before this one starts, and Cache, if true, opts
+// this action into the content-addressed cache described on runInDirs.
+type taskDef struct {
+	Name      string   `yaml:"name"`
+	Cmd       []string `yaml:"cmd,flow"`
+	DependsOn []string `yaml:"depends_on"`
+	Cache     bool     `yaml:"cache"`
+}
+
+func (c config) findTask(action string) (taskDef, bool) {
+	for _, t := range c.Tasks {
+		if t.Name == action {
+			return t, true
+		}
+	}
+	return taskDef{}, false
 }
 
 func (c config) commandForAction(action string) []string {
+	if t, ok := c.findTask(action); ok {
+		return t.Cmd
+	}
 	t := reflect.TypeOf(c)
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
@@ -87,6 +165,22 @@ func (c config) commandForAction(action string) []string {
 	return nil
 }
 
+// dependsOn returns the task-level dependencies declared for action via
+// a tasks entry; builtin actions (test, lint, ...) have none.
+func (c config) dependsOn(action string) []string {
+	t, _ := c.findTask(action)
+	return t.DependsOn
+}
+
+// cacheable reports whether action opted into the content-addressed
+// cache via a tasks entry's cache field; builtin actions never cache,
+// since re-running go test/golangci-lint/govulncheck is already how
+// users expect those to behave.
+func (c config) cacheable(action string) bool {
+	t, ok := c.findTask(action)
+	return ok && t.Cache
+}
+
 var multimod_root string
 
 func init() {
@@ -136,24 +230,337 @@ type script struct {
 }
 
 var (
-	configFileFlag        string
-	modulesFlag           bool
-	verboseFlag           bool
-	goworkUpdateFlag      bool
-	localGoWorkUpdateFlag bool
+	configFileFlag      string
+	modulesFlag         bool
+	verboseFlag         bool
+	goworkUpdateFlag    bool
+	onlyFlag            string
+	dryRunFlag          bool
+	jobsFlag            int
+	reportFlag          string
+	graphFlag           bool
+	failFastFlag        bool
+	continueOnErrorFlag bool
+	includeFlag         globList
+	excludeFlag         globList
+	changedSinceFlag    string
 )
 
 func init() {
 	flag.BoolVar(&modulesFlag, "modules", false, "print modules in this repo")
 	flag.StringVar(&configFileFlag, "config", "", "config file")
 	flag.BoolVar(&verboseFlag, "verbose", false, "verbose output")
-	flag.BoolVar(&goworkUpdateFlag, "gowork-update", false, "update all go.work references to latest git hash")
-	flag.BoolVar(&localGoWorkUpdateFlag, "gowork-update-local", false, "update go.work references for the specified local modules (comman separated) only")
+	flag.BoolVar(&goworkUpdateFlag, "gowork-update", false, "update all go.work references to latest git hash, in dependency order")
+	flag.StringVar(&onlyFlag, "only", "", "comma separated list of go.work module paths to restrict a -gowork-update to, along with their local dependencies")
+	flag.BoolVar(&dryRunFlag, "dry-run", false, "with -gowork-update, print the go get/mod tidy commands that would be run without running them")
+	flag.IntVar(&jobsFlag, "jobs", runtime.GOMAXPROCS(0), "number of modules to run an action in concurrently")
+	flag.IntVar(&jobsFlag, "j", runtime.GOMAXPROCS(0), "alias for -jobs")
+	flag.StringVar(&reportFlag, "report", "", "write a JUnit XML file, a JSON summary and per-module stdout/stderr logs to this directory")
+	flag.BoolVar(&graphFlag, "graph", false, "print the inter-module dependency graph (derived from each module's go.mod require directives) as Graphviz and exit")
+	flag.BoolVar(&failFastFlag, "fail-fast", false, "stop running further modules/actions as soon as one fails; this is the default, so this flag mainly exists to override a future default change and to pair with -continue-on-error's mutual-exclusion check")
+	flag.BoolVar(&continueOnErrorFlag, "continue-on-error", false, "keep running every remaining module and action even after a failure, so -report has complete results; mutually exclusive with -fail-fast")
+	flag.Var(&includeFlag, "include", "glob pattern, matched against a module's directory or its go.mod module path, to include; may be repeated; if given, only matching modules are considered")
+	flag.Var(&excludeFlag, "exclude", "glob pattern, matched against a module's directory or its go.mod module path, to exclude; may be repeated and takes precedence over -include")
+	flag.StringVar(&changedSinceFlag, "changed-since", "", "git ref; restrict modules to those containing a file changed since ref (via 'git diff --name-only ref...HEAD'), plus any module that locally depends on one of them")
+}
+
+// globList accumulates repeated occurrences of a glob-pattern flag,
+// such as -include and -exclude.
+type globList []string
+
+func (g *globList) String() string { return strings.Join(*g, ",") }
+
+func (g *globList) Set(v string) error {
+	*g = append(*g, v)
+	return nil
+}
+
+// moduleResult is one module's outcome for one action, recorded for
+// the -report summary.
+type moduleResult struct {
+	Module     string           `json:"module"`
+	Action     string           `json:"action"`
+	Command    string           `json:"command,omitempty"`
+	Start      time.Time        `json:"start,omitempty"`
+	End        time.Time        `json:"end,omitempty"`
+	DurationMS int64            `json:"duration_ms,omitempty"`
+	ExitCode   int              `json:"exit_code"`
+	StdoutTail string           `json:"stdout_tail,omitempty"`
+	StderrTail string           `json:"stderr_tail,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	Skipped    bool             `json:"skipped,omitempty"`
+	SkipReason string           `json:"skip_reason,omitempty"`
+	Tests      []testCaseResult `json:"tests,omitempty"`
+}
+
+// testCaseResult is one Go test function parsed from `go test -json`
+// output, turned into a JUnit <testcase> by buildJUnit.
+type testCaseResult struct {
+	Name    string  `json:"name"`
+	Package string  `json:"package"`
+	Seconds float64 `json:"seconds"`
+	Passed  bool    `json:"passed"`
+	Skipped bool    `json:"skipped"`
+	Output  string  `json:"output,omitempty"`
+}
+
+// report accumulates moduleResults across a run for -report, safe for
+// concurrent use by the -jobs worker pool. When dir is non-empty,
+// runInDirReport also captures each module's full stdout/stderr to a
+// file under dir, and save writes dir/summary.json and dir/junit.xml.
+type report struct {
+	mu      sync.Mutex
+	dir     string
+	Results []moduleResult `json:"results"`
+}
+
+func (r *report) add(res moduleResult) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Results = append(r.Results, res)
+}
+
+// logPath returns the path that a module/action's stdout or stderr
+// should be captured to, or "" if r isn't configured to write logs.
+func (r *report) logPath(module, action, stream string) string {
+	if r == nil || r.dir == "" {
+		return ""
+	}
+	sanitize := strings.NewReplacer("/", "_", string(filepath.Separator), "_", " ", "_")
+	return filepath.Join(r.dir, sanitize.Replace(module)+"-"+action+"."+stream)
+}
+
+func (r *report) writeLog(module, action, stream string, data []byte) error {
+	path := r.logPath(module, action, stream)
+	if path == "" {
+		return nil
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// save writes r.dir/summary.json (the full moduleResult list) and
+// r.dir/junit.xml (see buildJUnit), for ingestion by CI test reporters.
+// It is a no-op if r has no dir configured.
+func (r *report) save() error {
+	if r == nil || r.dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r, "", "  ")
+	results := append([]moduleResult(nil), r.Results...)
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(r.dir, "summary.json"), data, 0600); err != nil {
+		return err
+	}
+	xmlData, err := xml.MarshalIndent(buildJUnit(results), "", "  ")
+	if err != nil {
+		return err
+	}
+	xmlData = append([]byte(xml.Header), xmlData...)
+	return os.WriteFile(filepath.Join(r.dir, "junit.xml"), xmlData, 0644)
+}
+
+// junitTestCase, junitFailure, junitSkipped, junitTestSuite and
+// junitTestSuites mirror the subset of the JUnit XML schema that CI
+// test reporters (GitHub Actions, Jenkins) actually read.
+type junitTestCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct{}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+// buildJUnit turns results into a JUnit report: a module/action whose
+// command was `go test` (and so has per-test results in Tests) becomes
+// one testsuite with one testcase per Go test function; anything else
+// becomes a single-testcase testsuite representing the module/action
+// as a whole, so every action type is represented in the JUnit output.
+func buildJUnit(results []moduleResult) junitTestSuites {
+	var suites junitTestSuites
+	for _, res := range results {
+		suite := junitTestSuite{Name: res.Module + "/" + res.Action}
+		if len(res.Tests) > 0 {
+			for _, t := range res.Tests {
+				tc := junitTestCase{ClassName: t.Package, Name: t.Name, Time: fmt.Sprintf("%.3f", t.Seconds)}
+				switch {
+				case t.Skipped:
+					tc.Skipped = &junitSkipped{}
+					suite.Skipped++
+				case !t.Passed:
+					tc.Failure = &junitFailure{Message: "test failed", Text: t.Output}
+					suite.Failures++
+				}
+				suite.Tests++
+				suite.TestCases = append(suite.TestCases, tc)
+			}
+		} else {
+			tc := junitTestCase{ClassName: res.Module, Name: res.Action, Time: fmt.Sprintf("%.3f", float64(res.DurationMS)/1000)}
+			switch {
+			case res.Skipped:
+				tc.Skipped = &junitSkipped{}
+				suite.Skipped++
+			case res.Error != "":
+				tc.Failure = &junitFailure{Message: res.Error, Text: res.StderrTail}
+				suite.Failures++
+			}
+			suite.Tests = 1
+			suite.TestCases = append(suite.TestCases, tc)
+		}
+		suite.Time = fmt.Sprintf("%.3f", float64(res.DurationMS)/1000)
+		suites.Suites = append(suites.Suites, suite)
+	}
+	return suites
+}
+
+// parseGoTestJSON parses the line-delimited test2json events produced
+// by `go test -json` on stdout into one testCaseResult per Go test
+// function; package-level events (which have no Test field) are
+// ignored since the JUnit testsuite already represents the package.
+func parseGoTestJSON(stdout string) []testCaseResult {
+	type event struct {
+		Action  string  `json:"Action"`
+		Package string  `json:"Package"`
+		Test    string  `json:"Test"`
+		Elapsed float64 `json:"Elapsed"`
+		Output  string  `json:"Output"`
+	}
+	type accum struct {
+		pkg     string
+		test    string
+		output  strings.Builder
+		action  string
+		elapsed float64
+	}
+	tests := map[string]*accum{}
+	var order []string
+	for _, line := range strings.Split(stdout, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var ev event
+		if err := json.Unmarshal([]byte(line), &ev); err != nil || ev.Test == "" {
+			continue
+		}
+		// Key by package+test: the same test name (e.g. TestBasic) can
+		// exist in more than one package within a module, and those
+		// would otherwise collide into a single accum.
+		key := ev.Package + "/" + ev.Test
+		a, ok := tests[key]
+		if !ok {
+			a = &accum{pkg: ev.Package, test: ev.Test}
+			tests[key] = a
+			order = append(order, key)
+		}
+		switch ev.Action {
+		case "output":
+			a.output.WriteString(ev.Output)
+		case "pass", "fail", "skip":
+			a.action = ev.Action
+			a.elapsed = ev.Elapsed
+		}
+	}
+	results := make([]testCaseResult, 0, len(order))
+	for _, key := range order {
+		a := tests[key]
+		results = append(results, testCaseResult{
+			Name:    a.test,
+			Package: a.pkg,
+			Seconds: a.elapsed,
+			Passed:  a.action == "pass",
+			Skipped: a.action == "skip",
+			Output:  a.output.String(),
+		})
+	}
+	return results
+}
+
+const (
+	ansiGreen  = "\033[32m"
+	ansiRed    = "\033[31m"
+	ansiYellow = "\033[33m"
+	ansiReset  = "\033[0m"
+)
+
+// printSummaryTable prints a colorized pass/fail/skip table grouped by
+// module, as a quick-glance companion to the files -report writes.
+func printSummaryTable(rep *report) {
+	rep.mu.Lock()
+	results := append([]moduleResult(nil), rep.Results...)
+	rep.mu.Unlock()
+
+	byModule := map[string][]moduleResult{}
+	var modules []string
+	for _, res := range results {
+		if _, ok := byModule[res.Module]; !ok {
+			modules = append(modules, res.Module)
+		}
+		byModule[res.Module] = append(byModule[res.Module], res)
+	}
+	sort.Strings(modules)
+
+	fmt.Println("\nSummary:")
+	for _, mod := range modules {
+		fmt.Printf("  %s\n", mod)
+		for _, res := range byModule[mod] {
+			status, color := "ok", ansiGreen
+			switch {
+			case res.Skipped:
+				status, color = "skip ("+res.SkipReason+")", ansiYellow
+			case res.Error != "":
+				status, color = "FAIL", ansiRed
+			}
+			fmt.Printf("    %-12s %s%s%s\n", res.Action, color, status, ansiReset)
+		}
+	}
+}
+
+// tail returns at most the last n bytes of s, for embedding an excerpt
+// of a module's output in the JSON report without bloating it.
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[len(s)-n:]
 }
 
 func main() {
 	ctx := context.Background()
 	flag.Parse()
+	if failFastFlag && continueOnErrorFlag {
+		done("flags", fmt.Errorf("-fail-fast and -continue-on-error are mutually exclusive"))
+	}
 
 	cfg, err := readConfig()
 	if err != nil {
@@ -164,16 +571,32 @@ func main() {
 	if err != nil {
 		done("finding modules", err)
 	}
+	mods, err = filterModules(mods, includeFlag, excludeFlag)
+	if err != nil {
+		done("filtering modules", err)
+	}
+	if changedSinceFlag != "" {
+		mods, err = changedModules(ctx, changedSinceFlag, mods)
+		if err != nil {
+			done("selecting changed modules", err)
+		}
+	}
 	if goworkUpdateFlag {
-		if err := goworkUpdate(ctx, nil); err != nil {
+		var only []string
+		if onlyFlag != "" {
+			only = strings.Split(onlyFlag, ",")
+		}
+		if err := goworkUpdate(ctx, only, dryRunFlag); err != nil {
 			done("updating go.work references", err)
 		}
 		return
 	}
-	if localGoWorkUpdateFlag {
-		if err := goworkUpdate(ctx, flag.Args()); err != nil {
-			done("updating go.work references", err)
+	if graphFlag {
+		graph, err := buildModuleGraph(mods)
+		if err != nil {
+			done("building module graph", err)
 		}
+		printGraphviz(graph)
 		return
 	}
 
@@ -182,8 +605,12 @@ func main() {
 		fmt.Println(strings.Join(mods, " "))
 		return
 	}
+	orderedActions, err := expandActionOrder(cfg, actions)
+	if err != nil {
+		done("ordering actions", err)
+	}
 	var scripts []script
-	for _, action := range actions {
+	for _, action := range orderedActions {
 		command := cfg.commandForAction(action)
 		if len(command) == 0 {
 			done("unsupported action", fmt.Errorf("%q", action))
@@ -191,6 +618,29 @@ func main() {
 		command = expand(command)
 		scripts = append(scripts, script{action, command})
 	}
+	var rep *report
+	if reportFlag != "" {
+		rep = &report{dir: reportFlag}
+	}
+	var anyFailed bool
+	fail := func(msg string, err error) {
+		anyFailed = true
+		fmt.Printf("multimod: %s: %v\n", msg, err)
+		if continueOnErrorFlag {
+			return
+		}
+		// Fail fast, but -report exists specifically to capture what
+		// happened on failure, so write it before exiting rather than
+		// only on a clean run.
+		if rep != nil {
+			if err := rep.save(); err != nil {
+				fmt.Printf("multimod: %s: %v\n", "writing report", err)
+			} else {
+				printSummaryTable(rep)
+			}
+		}
+		os.Exit(1)
+	}
 	for _, script := range scripts {
 		exclusions := cfg.Exclusions[script.action]
 		allowedMods := []string{}
@@ -199,17 +649,73 @@ func main() {
 				allowedMods = append(allowedMods, mod)
 			} else {
 				fmt.Printf("Excluding module %q from action %q\n", mod, script.action)
+				rep.add(moduleResult{Module: mod, Action: script.action, Skipped: true, SkipReason: "excluded"})
 			}
 		}
-		if err := runInDirs(ctx, allowedMods, script.action, script.commands); err != nil {
-			done(fmt.Sprintf("running %v", script.action), err)
+		switch script.action {
+		case "vendor":
+			if err := vendorAction(ctx, allowedMods, script.commands, rep, jobsFlag, cfg.cacheable(script.action)); err != nil {
+				fail("running vendor", err)
+			}
+		case "prewarm":
+			if err := prewarmAction(ctx, allowedMods, jobsFlag); err != nil {
+				fail("running prewarm", err)
+			}
+		default:
+			if err := runInDirs(ctx, allowedMods, script.action, script.commands, rep, jobsFlag, cfg.cacheable(script.action)); err != nil {
+				fail(fmt.Sprintf("running %v", script.action), err)
+			}
+		}
+	}
+	if rep != nil {
+		if err := rep.save(); err != nil {
+			done("writing report", err)
 		}
+		printSummaryTable(rep)
+	}
+	if anyFailed {
+		os.Exit(1)
 	}
 }
 
+// expandActionOrder returns actions together with every action they
+// transitively depend on (via a tasks entry's depends_on), ordered so
+// that a dependency always appears before the action(s) that need it;
+// actions not declared as a task (the builtin test/lint/govuln/...
+// trio) have no dependencies of their own but can still be depended on.
+func expandActionOrder(cfg config, actions []string) ([]string, error) {
+	deps := map[string][]string{}
+	var walk func(action string)
+	walk = func(action string) {
+		if _, ok := deps[action]; ok {
+			return
+		}
+		d := cfg.dependsOn(action)
+		deps[action] = d
+		for _, dep := range d {
+			walk(dep)
+		}
+	}
+	for _, action := range actions {
+		walk(action)
+	}
+	return topoSort(deps)
+}
+
+// modules returns the module directories that multimod operates on: if
+// a go.work file exists at the repository root, its 'use' directives
+// are authoritative (so a module deliberately left out of go.work is
+// left out here too); otherwise every directory containing a go.mod is
+// found by walking the filesystem.
 func modules() ([]string, error) {
-	var dirs []string
-	err := filepath.Walk(".", func(path string, info fs.FileInfo, err error) error {
+	dirs, ok, err := goworkModules()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return dirs, nil
+	}
+	err = filepath.Walk(".", func(path string, info fs.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -224,6 +730,140 @@ func modules() ([]string, error) {
 	return dirs, err
 }
 
+// goworkModules returns the module directories named by go.work's use
+// directives; ok is false (with no error) if there is no go.work file,
+// in which case modules() falls back to walking the filesystem.
+func goworkModules() ([]string, bool, error) {
+	contents, err := os.ReadFile("go.work")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	wk, err := modfile.ParseWork("go.work", contents, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("parsing go.work: %w", err)
+	}
+	dirs := make([]string, 0, len(wk.Use))
+	for _, u := range wk.Use {
+		dirs = append(dirs, filepath.Clean(u.Path))
+	}
+	sort.Strings(dirs)
+	return dirs, true, nil
+}
+
+// filterModules returns the subset of dirs that match at least one of
+// include (or all of dirs, if include is empty) and none of exclude,
+// matching -include/-exclude glob patterns against either a module's
+// directory or its go.mod module path.
+func filterModules(dirs []string, include, exclude []string) ([]string, error) {
+	if len(include) == 0 && len(exclude) == 0 {
+		return dirs, nil
+	}
+	var out []string
+	for _, dir := range dirs {
+		modPath := dir
+		if mf, err := readGoMod(dir); err == nil {
+			modPath = mf.Module.Mod.Path
+		}
+		excluded, err := matchesAny(exclude, dir, modPath)
+		if err != nil {
+			return nil, err
+		}
+		if excluded {
+			continue
+		}
+		if len(include) > 0 {
+			included, err := matchesAny(include, dir, modPath)
+			if err != nil {
+				return nil, err
+			}
+			if !included {
+				continue
+			}
+		}
+		out = append(out, dir)
+	}
+	return out, nil
+}
+
+// matchesAny reports whether dir or modPath matches any of patterns.
+func matchesAny(patterns []string, dir, modPath string) (bool, error) {
+	for _, p := range patterns {
+		for _, candidate := range [...]string{dir, modPath} {
+			matched, err := filepath.Match(p, candidate)
+			if err != nil {
+				return false, fmt.Errorf("invalid glob pattern %q: %w", p, err)
+			}
+			if matched {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// changedModules returns the subset of dirs that contain at least one
+// file changed since ref (per `git diff --name-only ref...HEAD`),
+// together with every module in dirs that locally depends on one of
+// them, directly or transitively — a change to a dependency can break
+// a dependent even when the dependent's own files are untouched.
+func changedModules(ctx context.Context, ref string, dirs []string) ([]string, error) {
+	var out strings.Builder
+	c := exec.CommandContext(ctx, "git", "diff", "--name-only", ref+"...HEAD")
+	c.Stderr = os.Stderr
+	c.Stdout = &out
+	if err := c.Run(); err != nil {
+		return nil, fmt.Errorf("git diff --name-only %v...HEAD: %w", ref, err)
+	}
+
+	// Longest directory first, so a file under a nested module is
+	// attributed to that module rather than to one of its parents.
+	sorted := append([]string(nil), dirs...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+
+	changed := map[string]bool{}
+	for _, line := range strings.Split(out.String(), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		file := filepath.Clean(line)
+		for _, dir := range sorted {
+			rel := filepath.Clean(dir)
+			if rel == "." || file == rel || strings.HasPrefix(file, rel+string(filepath.Separator)) {
+				changed[dir] = true
+				break
+			}
+		}
+	}
+
+	graph, err := buildModuleGraph(dirs)
+	if err != nil {
+		return nil, fmt.Errorf("building module dependency graph: %w", err)
+	}
+	reverse := map[string][]string{}
+	for dir, n := range graph {
+		for _, dep := range n.deps {
+			reverse[dep] = append(reverse[dep], dir)
+		}
+	}
+	selected := map[string]bool{}
+	for dir := range changed {
+		for node := range closureOf(reverse, dir) {
+			selected[node] = true
+		}
+	}
+	var result []string
+	for _, dir := range dirs {
+		if selected[dir] {
+			result = append(result, dir)
+		}
+	}
+	return result, nil
+}
+
 func splitCmd(cmdargs []string) [][]string {
 	var cmds [][]string
 	var cmd []string
@@ -241,10 +881,42 @@ func splitCmd(cmdargs []string) [][]string {
 	return cmds
 }
 
-func runInDirs(ctx context.Context, dirs []string, action string, cmdSpec []string) error {
+// runInDirs runs cmdSpec (one or more ';'-separated commands) in each
+// of dirs, up to jobs of them concurrently. Modules run in the order
+// imposed by their go.mod require directives on each other (a module
+// only starts once every local module it depends on has finished),
+// rather than all at once, so that e.g. a "generate" action in a
+// leaf module completes before it runs in a module that depends on it.
+// Each module's stdout and stderr are buffered and printed as a single
+// block once it finishes, so output doesn't interleave across
+// concurrent modules, and (when rep is non-nil) a moduleResult is
+// recorded for every module/action. If cacheable is true, a module is
+// skipped (and its cache entry reused) when the command and the
+// content of the module and its local dependency closure match a
+// previous successful run, per the cache described on cacheKeyFor.
+// Unless -continue-on-error was given, the first module to fail cancels
+// every module still running or waiting to start, and every module that
+// depends on it (directly or transitively) is skipped rather than run.
+func runInDirs(ctx context.Context, dirs []string, action string, cmdSpec []string, rep *report, jobs int, cacheable bool) error {
 	if len(cmdSpec) == 0 {
 		return fmt.Errorf("missing command")
 	}
+	if jobs < 1 {
+		jobs = 1
+	}
+	graph, err := buildModuleGraph(dirs)
+	if err != nil {
+		return fmt.Errorf("building module dependency graph: %w", err)
+	}
+	var cacheDir string
+	if cacheable {
+		if cacheDir, err = multimodCacheDir(); err != nil {
+			return fmt.Errorf("determining cache dir: %w", err)
+		}
+	}
+	stopOnFailure := !continueOnErrorFlag
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	allCmds := splitCmd(cmdSpec)
 	for _, cmdargs := range allCmds {
 		cmd := cmdargs[0]
@@ -252,20 +924,112 @@ func runInDirs(ctx context.Context, dirs []string, action string, cmdSpec []stri
 		if len(cmdargs) > 1 {
 			args = cmdargs[1:]
 		}
-		var errs errors.M
-		for _, dir := range dirs {
-			if err := runInDir(ctx, dir, cmd, args); err != nil {
-				fmt.Fprintf(os.Stderr, "%v: failed: %v\n", dir, err)
-				errs.Append(fmt.Errorf("action in %v: %v %v %w", dir, action, strings.Join(cmdargs, " "), err))
+		goTestJSON := rep != nil && rep.dir != "" && cmd == "go" && len(args) > 0 && args[0] == "test" && !slices.Contains(args, "-json")
+		if goTestJSON {
+			args = append(append([]string{}, args...), "-json")
+		}
+		commandLine := strings.Join(append([]string{cmd}, args...), " ")
+		var printMu sync.Mutex
+		run := func(dir string) error {
+			if stopOnFailure && runCtx.Err() != nil {
+				rep.add(moduleResult{Module: dir, Action: action, Command: commandLine, Skipped: true, SkipReason: "fail-fast: a previous failure stopped the run"})
+				fmt.Printf("%v... skipped (fail-fast)\n", dir)
+				return nil
+			}
+			var key string
+			if cacheable {
+				var kerr error
+				key, kerr = cacheKeyFor(action, commandLine, dir, graph)
+				if kerr == nil && cacheHit(cacheDir, key) {
+					rep.add(moduleResult{Module: dir, Action: action, Command: commandLine, Skipped: true, SkipReason: "cache hit"})
+					fmt.Printf("%v... cached\n", dir)
+					return nil
+				}
+			}
+			res, runErr := runInDirReport(runCtx, dir, action, commandLine, cmd, args, &printMu, rep, goTestJSON)
+			rep.add(res)
+			if runErr != nil {
+				if stopOnFailure {
+					cancel()
+				}
+				return fmt.Errorf("action in %v: %v %v %w", dir, action, commandLine, runErr)
+			}
+			if cacheable && key != "" {
+				if err := cacheStore(cacheDir, key, dir, action); err != nil {
+					fmt.Printf("%v: failed to write cache entry: %v\n", dir, err)
+				}
 			}
+			return nil
+		}
+		skip := func(dir string) {
+			rep.add(moduleResult{Module: dir, Action: action, Command: commandLine, Skipped: true, SkipReason: "a local dependency failed"})
+			fmt.Printf("%v... skipped (a local dependency failed)\n", dir)
 		}
-		if err := errs.Err(); err != nil {
+		if err := runDAG(depModuleDeps(graph), jobs, stopOnFailure, run, skip); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// runInDirReport runs binary in dir, buffering its output and
+// printing it as a single block (serialized by printMu) once it
+// completes, and returns a moduleResult describing the outcome. When
+// rep is configured with a -report dir, the full stdout/stderr are
+// also written there; when parseGoTest is true (binary/args is a `go
+// test -json` invocation), stdout is additionally parsed into
+// res.Tests via parseGoTestJSON.
+func runInDirReport(ctx context.Context, dir, action, commandLine, binary string, args []string, printMu *sync.Mutex, rep *report, parseGoTest bool) (moduleResult, error) {
+	res := moduleResult{Module: dir, Action: action, Command: commandLine, Start: time.Now()}
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	res.End = time.Now()
+	res.DurationMS = res.End.Sub(res.Start).Milliseconds()
+	res.StdoutTail = tail(stdout.String(), 4096)
+	res.StderrTail = tail(stderr.String(), 4096)
+	if rep != nil && rep.dir != "" {
+		if werr := rep.writeLog(dir, action, "stdout", stdout.Bytes()); werr != nil {
+			fmt.Printf("%v: failed to write stdout log: %v\n", dir, werr)
+		}
+		if werr := rep.writeLog(dir, action, "stderr", stderr.Bytes()); werr != nil {
+			fmt.Printf("%v: failed to write stderr log: %v\n", dir, werr)
+		}
+	}
+	if parseGoTest {
+		res.Tests = parseGoTestJSON(stdout.String())
+	}
+
+	printMu.Lock()
+	fmt.Printf("%v...\n", dir)
+	if verboseFlag {
+		fmt.Printf("%v %v\n", binary, strings.Join(args, " "))
+	}
+	os.Stdout.Write(stdout.Bytes())
+	os.Stderr.Write(stderr.Bytes())
+	if err == nil {
+		fmt.Printf("%v... ok\n", dir)
+	} else {
+		fmt.Printf("%v... failed\n", dir)
+	}
+	printMu.Unlock()
+
+	if err != nil {
+		res.Error = err.Error()
+		var exitErr *exec.ExitError
+		if stderrors.As(err, &exitErr) {
+			res.ExitCode = exitErr.ExitCode()
+		} else {
+			res.ExitCode = -1
+		}
+		return res, fmt.Errorf("%v %v: %w", binary, strings.Join(args, " "), err)
+	}
+	return res, nil
+}
+
 func runInDir(ctx context.Context, dir string, binary string, args []string) error {
 	fmt.Printf("%v...\n", dir)
 	if verboseFlag {
@@ -284,7 +1048,26 @@ func runInDir(ctx context.Context, dir string, binary string, args []string) err
 	return err
 }
 
-func goworkUpdate(ctx context.Context, internalModsToConsider []string) error {
+// modNode is a single go.work module, identified by its go.work use
+// path (e.g. "./foo"), along with the other nodes it depends on.
+type modNode struct {
+	path    string
+	modFile *modfile.File
+	hash    string
+	deps    []string // go.work paths of local modules this one requires
+}
+
+// goworkUpdate bumps every module listed in go.work's 'use' directives
+// to its current git hash, in reverse topological order so that a
+// module is only updated once every local module it requires has
+// already been updated, tidied, and committed - committing each bump
+// is what lets a dependent pick up its dependency's new hash rather
+// than the one it had at the start of the run, which is the entire
+// point of processing in reverse topological order. only, if
+// non-empty, restricts the modules actually updated to the transitive
+// closure (under the requires-relation) of the named go.work paths;
+// dryRun prints the planned commands instead of running them.
+func goworkUpdate(ctx context.Context, only []string, dryRun bool) error {
 	filename := "go.work"
 	contents, err := os.ReadFile(filename)
 	if err != nil {
@@ -300,84 +1083,452 @@ func goworkUpdate(ctx context.Context, internalModsToConsider []string) error {
 		return err
 	}
 
-	type perModUpdate struct {
-		mod    string
-		update string
-	}
-
-	updates := []perModUpdate{}
-	modFiles := map[string]*modfile.File{}
-	var internalMods, externalMods []string
+	nodes := map[string]*modNode{}
+	byModPath := map[string]string{} // module path -> go.work path
+	byDir := map[string]string{}     // cleaned absolute directory -> go.work path
 	for _, r := range wk.Use {
 		if r.Path == "." || strings.Contains(r.Path, "multimod") {
 			continue
 		}
-
+		mf, err := readGoMod(r.Path)
+		if err != nil {
+			return err
+		}
 		h, err := gitHashFor(ctx, r.Path)
 		if err != nil {
 			return fmt.Errorf("failed to get git hash for %v: %v", r.Path, err)
 		}
-		mod, err := readGoMod(r.Path)
-		if err != nil {
-			return err
+		nodes[r.Path] = &modNode{path: r.Path, modFile: mf, hash: h}
+		byModPath[mf.Module.Mod.Path] = r.Path
+		if abs, err := filepath.Abs(r.Path); err == nil {
+			byDir[filepath.Clean(abs)] = r.Path
 		}
-		modFiles[r.Path] = mod
-		updates = append(updates, perModUpdate{
-			mod:    mod.Module.Mod.Path,
-			update: mod.Module.Mod.Path + "@" + h,
-		})
-		if len(r.Path) > 2 && r.Path[0] == '.' && r.Path[1] == '/' {
-			internalMods = append(internalMods, r.Path)
-		} else {
-			externalMods = append(externalMods, r.Path)
+	}
+
+	for _, n := range nodes {
+		for _, req := range n.modFile.Require {
+			if depPath, ok := byModPath[req.Mod.Path]; ok && depPath != n.path {
+				if !slices.Contains(n.deps, depPath) {
+					n.deps = append(n.deps, depPath)
+				}
+			}
+		}
+		// a replace directive can point a require at a local module that
+		// isn't reachable by module path alone, e.g. a fork or a path
+		// that has since been renamed.
+		for _, rep := range n.modFile.Replace {
+			if rep.New.Path == "" || rep.New.Version != "" {
+				continue // not a filesystem replace
+			}
+			abs, err := filepath.Abs(filepath.Join(n.path, rep.New.Path))
+			if err != nil {
+				continue
+			}
+			depPath, ok := byDir[filepath.Clean(abs)]
+			if !ok || depPath == n.path {
+				continue
+			}
+			if !slices.Contains(n.deps, depPath) {
+				n.deps = append(n.deps, depPath)
+			}
 		}
 	}
 
-	// for external modules apply all updates to every module
-	// in this workspace.
-	for _, modpath := range externalMods {
-		for _, update := range updates {
-			if err := runInDir(ctx, modpath, "go", []string{"get", update.update}); err != nil {
-				return fmt.Errorf("%v: go get %v: failed %w", modpath, update.update, err)
+	order, err := topoSortModules(nodes)
+	if err != nil {
+		return err
+	}
+
+	selected := order
+	if len(only) > 0 {
+		named := map[string]bool{}
+		for _, m := range only {
+			for path := range nodes {
+				if filepath.Clean(path) == filepath.Clean(m) {
+					named[path] = true
+				}
 			}
-			if err := runInDir(ctx, modpath, "go", []string{"mod", "tidy"}); err != nil {
-				return fmt.Errorf("%v: go mod tidy: failed %w", modpath, err)
+		}
+		closure := closureOfModules(nodes, named)
+		selected = nil
+		for _, path := range order {
+			if closure[path] {
+				selected = append(selected, path)
 			}
 		}
 	}
 
-	if len(internalModsToConsider) == 0 {
+	for _, path := range selected {
+		n := nodes[path]
+		merged := []string{"get"}
+		for _, depPath := range n.deps {
+			dep := nodes[depPath]
+			merged = append(merged, dep.modFile.Module.Mod.Path+"@"+dep.hash)
+		}
+		if len(merged) == 1 {
+			continue // no local dependencies to bump
+		}
+		if dryRun {
+			fmt.Printf("%v: go %v\n", n.path, strings.Join(merged, " "))
+			fmt.Printf("%v: go mod tidy\n", n.path)
+			fmt.Printf("%v: git commit go.mod go.sum\n", n.path)
+			continue
+		}
+		if err := runInDir(ctx, n.path, "go", merged); err != nil {
+			return fmt.Errorf("%v: go %v: failed %w", n.path, strings.Join(merged, " "), err)
+		}
+		if err := runInDir(ctx, n.path, "go", []string{"mod", "tidy"}); err != nil {
+			return fmt.Errorf("%v: go mod tidy: failed %w", n.path, err)
+		}
+		if err := commitModuleBump(ctx, n.path); err != nil {
+			return fmt.Errorf("%v: %w", n.path, err)
+		}
+		h, err := gitHashFor(ctx, n.path)
+		if err != nil {
+			return fmt.Errorf("%v: failed to get git hash after bump: %v", n.path, err)
+		}
+		n.hash = h
+	}
+	return nil
+}
+
+// topoSort returns the keys of deps in reverse topological order (a
+// node's dependencies appear before it), or an error describing the
+// cycle if deps isn't a DAG. A node reachable only as another node's
+// dependency, and not itself a key of deps, is still included in the
+// result as a leaf.
+func topoSort(deps map[string][]string) ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := map[string]int{}
+	var order []string
+	var visit func(node string, stack []string) error
+	visit = func(node string, stack []string) error {
+		switch state[node] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle detected: %v -> %v", strings.Join(append(stack, node), " -> "), node)
+		}
+		state[node] = visiting
+		for _, dep := range deps[node] {
+			if err := visit(dep, append(stack, node)); err != nil {
+				return err
+			}
+		}
+		state[node] = visited
+		order = append(order, node)
 		return nil
 	}
-	cleaned := []string{}
-	for _, m := range internalModsToConsider {
-		cleaned = append(cleaned, filepath.Clean(m))
+	nodes := make([]string, 0, len(deps))
+	for node := range deps {
+		nodes = append(nodes, node)
 	}
-	// for internal modules only apply updates for other modules,
-	// avoid updating a module with itself.
-	for _, modpath := range internalMods {
-		if !slices.Contains(cleaned, filepath.Clean(modpath)) {
-			continue
+	sort.Strings(nodes) // deterministic iteration order
+	for _, node := range nodes {
+		if err := visit(node, nil); err != nil {
+			return nil, err
 		}
-		otherUpdates := []string{}
-		for _, update := range updates {
-			mf := modFiles[modpath]
-			if mf.Module.Mod.Path == update.mod {
-				fmt.Printf("Skipping update of %v in %v to itself\n", update.mod, modpath)
-				continue
+	}
+	return order, nil
+}
+
+// topoSortModules returns the go.work paths of nodes in reverse
+// topological order (a module's dependencies appear before it), or an
+// error describing the cycle if the dependency graph isn't a DAG.
+func topoSortModules(nodes map[string]*modNode) ([]string, error) {
+	return topoSort(moduleDeps(nodes))
+}
+
+// closureOf returns start together with every node transitively
+// reachable from it by following deps.
+func closureOf(deps map[string][]string, start string) map[string]bool {
+	closure := map[string]bool{}
+	var add func(node string)
+	add = func(node string) {
+		if closure[node] {
+			return
+		}
+		closure[node] = true
+		for _, dep := range deps[node] {
+			add(dep)
+		}
+	}
+	add(start)
+	return closure
+}
+
+// closureOfModules returns named together with every local module
+// transitively required by a module in named.
+func closureOfModules(nodes map[string]*modNode, named map[string]bool) map[string]bool {
+	deps := moduleDeps(nodes)
+	closure := map[string]bool{}
+	for path := range named {
+		for node := range closureOf(deps, path) {
+			closure[node] = true
+		}
+	}
+	return closure
+}
+
+// moduleDeps projects a modNode graph down to the plain adjacency map
+// that topoSort and closureOf operate on.
+func moduleDeps(nodes map[string]*modNode) map[string][]string {
+	deps := make(map[string][]string, len(nodes))
+	for path, n := range nodes {
+		deps[path] = n.deps
+	}
+	return deps
+}
+
+// depModuleDeps projects a depNode graph (as built by buildModuleGraph)
+// down to the plain adjacency map that topoSort, closureOf and runDAG
+// operate on; the depNode equivalent of moduleDeps.
+func depModuleDeps(nodes map[string]*depNode) map[string][]string {
+	deps := make(map[string][]string, len(nodes))
+	for dir, n := range nodes {
+		deps[dir] = n.deps
+	}
+	return deps
+}
+
+// depNode is a single module directory found by modules(), along with
+// the other modules in the same walk that its go.mod require
+// directives point at.
+type depNode struct {
+	dir     string
+	modPath string
+	deps    []string // directories, from dirs, that this module requires
+}
+
+// buildModuleGraph parses the go.mod in each of dirs and returns the
+// dependency graph between them: an edge from a module to another
+// module in dirs exists whenever the former's go.mod require
+// directives name the latter's module path. Requires on modules
+// outside dirs (e.g. third-party dependencies) are not represented,
+// since runInDirs only needs to order modules it's actually going to
+// run an action in.
+func buildModuleGraph(dirs []string) (map[string]*depNode, error) {
+	nodes := make(map[string]*depNode, len(dirs))
+	modFiles := make(map[string]*modfile.File, len(dirs))
+	byModPath := map[string]string{} // module path -> directory
+	for _, dir := range dirs {
+		mf, err := readGoMod(dir)
+		if err != nil {
+			return nil, fmt.Errorf("reading go.mod for %v: %w", dir, err)
+		}
+		modFiles[dir] = mf
+		nodes[dir] = &depNode{dir: dir, modPath: mf.Module.Mod.Path}
+		byModPath[mf.Module.Mod.Path] = dir
+	}
+	for dir, n := range nodes {
+		for _, req := range modFiles[dir].Require {
+			if depDir, ok := byModPath[req.Mod.Path]; ok && depDir != dir {
+				if !slices.Contains(n.deps, depDir) {
+					n.deps = append(n.deps, depDir)
+				}
 			}
-			otherUpdates = append(otherUpdates, update.update)
 		}
-		merged := []string{"get"}
-		merged = append(merged, otherUpdates...)
-		if err := runInDir(ctx, modpath, "go", merged); err != nil {
-			return fmt.Errorf("%v: go get %v: failed %w", modpath, merged, err)
+	}
+	return nodes, nil
+}
+
+// printGraphviz prints graph as a Graphviz "dot" digraph, with an edge
+// from a module to each local module it depends on, for piping into
+// e.g. `dot -Tsvg` to visualize the repository's module structure.
+func printGraphviz(graph map[string]*depNode) {
+	dirs := make([]string, 0, len(graph))
+	for dir := range graph {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	fmt.Println("digraph multimod {")
+	fmt.Println("  rankdir=LR;")
+	for _, dir := range dirs {
+		fmt.Printf("  %q;\n", dir)
+	}
+	for _, dir := range dirs {
+		for _, dep := range graph[dir].deps {
+			fmt.Printf("  %q -> %q;\n", dir, dep)
 		}
-		if err := runInDir(ctx, modpath, "go", []string{"mod", "tidy"}); err != nil {
-			return fmt.Errorf("%v: go mod tidy: failed %w", modpath, err)
+	}
+	fmt.Println("}")
+}
+
+// runDAG runs run for every node in deps, such that a node doesn't
+// start until every node it depends on (directly or transitively) has
+// finished, with up to jobs nodes running concurrently. If skipDependents
+// is true and run returns an error for a node, every node that depends
+// on it (transitively) is passed to skip instead of run; if false, a
+// node's dependents run regardless of whether it failed (used by
+// -continue-on-error, so a run gets results for every module even after
+// a failure). Every error returned by run is collected into an
+// errors.M, which is returned once every node has either run or been
+// skipped.
+func runDAG(deps map[string][]string, jobs int, skipDependents bool, run func(node string) error, skip func(node string)) error {
+	if jobs < 1 {
+		jobs = 1
+	}
+	doneCh := make(map[string]chan struct{}, len(deps))
+	for node := range deps {
+		doneCh[node] = make(chan struct{})
+	}
+	var (
+		errs   errors.M
+		errsMu sync.Mutex
+		failed sync.Map // node -> true, read once every dependency's doneCh has closed
+		wg     sync.WaitGroup
+		sem    = make(chan struct{}, jobs)
+	)
+	for node := range deps {
+		node := node
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, dep := range deps[node] {
+				<-doneCh[dep]
+			}
+			defer close(doneCh[node])
+			if skipDependents {
+				for _, dep := range deps[node] {
+					if _, ok := failed.Load(dep); ok {
+						failed.Store(node, true)
+						skip(node)
+						return
+					}
+				}
+			}
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if err := run(node); err != nil {
+				failed.Store(node, true)
+				errsMu.Lock()
+				errs.Append(err)
+				errsMu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errs.Err()
+}
+
+// multimodCacheDir returns (creating if necessary) the directory that
+// the content-addressed action cache is stored under, honoring
+// $XDG_CACHE_HOME when set.
+func multimodCacheDir() (string, error) {
+	base := os.Getenv("XDG_CACHE_HOME")
+	if base == "" {
+		var err error
+		base, err = os.UserCacheDir()
+		if err != nil {
+			return "", err
 		}
 	}
-	return nil
+	dir := filepath.Join(base, "multimod")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// hashModuleDir hashes the contents of every *.go, go.mod and go.sum
+// file in dir, skipping vendor/.git and not descending into any nested
+// directory that has its own go.mod (a separate module, hashed on its
+// own account when it's itself part of the dependency closure).
+func hashModuleDir(dir string) (string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path == dir {
+				return nil
+			}
+			if name := d.Name(); name == "vendor" || name == ".git" {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, ".go") || d.Name() == "go.mod" || d.Name() == "go.sum" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+	h := sha256.New()
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\n", f)
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cacheKeyFor returns a content-addressed key for running action
+// (command commandLine) in dir: it hashes dir together with every
+// module in its local dependency closure (per graph), so that a change
+// to a dependency invalidates the cache just as a change to dir itself
+// would.
+func cacheKeyFor(action, commandLine, dir string, graph map[string]*depNode) (string, error) {
+	closure := closureOf(depModuleDeps(graph), dir)
+	dirs := make([]string, 0, len(closure))
+	for d := range closure {
+		dirs = append(dirs, d)
+	}
+	sort.Strings(dirs)
+	h := sha256.New()
+	fmt.Fprintf(h, "action=%s\ncommand=%s\n", action, commandLine)
+	for _, d := range dirs {
+		sum, err := hashModuleDir(d)
+		if err != nil {
+			return "", fmt.Errorf("hashing %v: %w", d, err)
+		}
+		fmt.Fprintf(h, "%s=%s\n", d, sum)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// cacheEntry records a successful run, so cacheHit can confirm a
+// cache file actually corresponds to a completed run rather than e.g.
+// a truncated write.
+type cacheEntry struct {
+	Module string `json:"module"`
+	Action string `json:"action"`
+}
+
+func cachePath(cacheDir, key string) string {
+	return filepath.Join(cacheDir, key+".json")
+}
+
+// cacheHit reports whether key already has a recorded successful run.
+func cacheHit(cacheDir, key string) bool {
+	data, err := os.ReadFile(cachePath(cacheDir, key))
+	if err != nil {
+		return false
+	}
+	var entry cacheEntry
+	return json.Unmarshal(data, &entry) == nil && entry.Module != "" && entry.Action != ""
+}
+
+func cacheStore(cacheDir, key, module, action string) error {
+	data, err := json.Marshal(cacheEntry{Module: module, Action: action})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(cachePath(cacheDir, key), data, 0644)
 }
 
 func readGoMod(path string) (*modfile.File, error) {
@@ -390,13 +1541,336 @@ func readGoMod(path string) (*modfile.File, error) {
 }
 
 func gitHashFor(ctx context.Context, path string) (string, error) {
+	out, err := gitOutput(ctx, path, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return out[:8], nil
+}
+
+// gitOutput runs git with args in dir and returns its trimmed stdout.
+func gitOutput(ctx context.Context, dir string, args ...string) (string, error) {
+	var out strings.Builder
+	c := exec.CommandContext(ctx, "git", args...)
+	c.Dir = dir
+	c.Stderr = os.Stderr
+	c.Stdout = &out
+	if err := c.Run(); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// commitModuleBump commits dir's go.mod and go.sum after a goworkUpdate
+// bump, so that dependents processed later in the reverse topological
+// order pick up this module's new git hash rather than the one it had
+// before the bump - which is the entire point of the reverse
+// topological ordering. It's a no-op if the bump left go.mod and
+// go.sum unchanged (e.g. the module had no local dependency to bump).
+func commitModuleBump(ctx context.Context, dir string) error {
+	status, err := gitOutput(ctx, dir, "status", "--porcelain", "--", "go.mod", "go.sum")
+	if err != nil {
+		return fmt.Errorf("checking for changes: %w", err)
+	}
+	if status == "" {
+		return nil
+	}
+	if err := runInDir(ctx, dir, "git", []string{"add", "go.mod", "go.sum"}); err != nil {
+		return fmt.Errorf("staging go.mod/go.sum: %w", err)
+	}
+	if err := runInDir(ctx, dir, "git", []string{"commit", "-m", "multimod: bump local module dependencies"}); err != nil {
+		return fmt.Errorf("committing go.mod/go.sum: %w", err)
+	}
+	return nil
+}
+
+// vendorAction runs cmdSpec (normally "go mod vendor") in every dir and
+// then merges the resulting per-module vendor/ trees into a single
+// workspace-level vendor/ directory at the repository root.
+func vendorAction(ctx context.Context, dirs []string, cmdSpec []string, rep *report, jobs int, cacheable bool) error {
+	if err := runInDirs(ctx, dirs, "vendor", cmdSpec, rep, jobs, cacheable); err != nil {
+		return err
+	}
+	return mergeWorkspaceVendor(dirs)
+}
+
+// mergeWorkspaceVendor merges the vendor/ directory of every module in
+// dirs into a single "vendor" directory at the repository root,
+// deduplicating identical files by content hash. Two modules vendoring
+// different versions of the same package is flagged as a conflict and
+// the first copy encountered (in dirs order) is kept.
+//
+// vendor/modules.txt is not a vendored file like the others: it's a
+// manifest whose content legitimately differs between modules (each
+// lists only the packages that module itself vendors), so it's merged
+// separately by unioning every module's stanzas rather than by the
+// first-copy-wins content dedup used for everything else - otherwise
+// the merged tree ends up with every module's vendored files but a
+// manifest describing only the first module's, and
+// `go build -mod=vendor` fails with "inconsistent vendoring".
+func mergeWorkspaceVendor(dirs []string) error {
+	const workspaceVendor = "vendor"
+	hashes := map[string]string{} // path relative to vendor/ -> sha256 hex already merged
+	var merged, conflicts int
+	for _, dir := range dirs {
+		vendorDir := filepath.Join(dir, "vendor")
+		if info, err := os.Stat(vendorDir); err != nil || !info.IsDir() {
+			continue
+		}
+		err := filepath.WalkDir(vendorDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(vendorDir, path)
+			if err != nil {
+				return err
+			}
+			if rel == "modules.txt" {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			sum := sha256.Sum256(data)
+			hexSum := hex.EncodeToString(sum[:])
+			if existing, ok := hashes[rel]; ok {
+				if existing != hexSum {
+					fmt.Printf("vendor: conflicting content for %v from %v, keeping the first copy merged\n", rel, dir)
+					conflicts++
+				}
+				return nil
+			}
+			hashes[rel] = hexSum
+			dest := filepath.Join(workspaceVendor, rel)
+			if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(dest, data, 0644); err != nil {
+				return err
+			}
+			merged++
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("merging vendor tree for %v: %w", dir, err)
+		}
+	}
+	if err := mergeModulesTxt(dirs, workspaceVendor); err != nil {
+		return fmt.Errorf("merging vendor/modules.txt: %w", err)
+	}
+	fmt.Printf("vendor: merged %d files into %v/ (%d conflicts)\n", merged, workspaceVendor, conflicts)
+	return nil
+}
+
+// mergeModulesTxt unions the "# module version" stanzas of every
+// module's vendor/modules.txt into a single manifest at
+// <workspaceVendor>/modules.txt, keeping the first stanza encountered
+// (in dirs order) for any module required by more than one of them.
+func mergeModulesTxt(dirs []string, workspaceVendor string) error {
+	seen := map[string]bool{}
+	var stanzas []string
+	for _, dir := range dirs {
+		data, err := os.ReadFile(filepath.Join(dir, "vendor", "modules.txt"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		keys, byKey := splitModulesTxtStanzas(string(data))
+		for _, key := range keys {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			stanzas = append(stanzas, byKey[key])
+		}
+	}
+	if len(stanzas) == 0 {
+		return nil
+	}
+	content := strings.Join(stanzas, "\n")
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return os.WriteFile(filepath.Join(workspaceVendor, "modules.txt"), []byte(content), 0644)
+}
+
+// splitModulesTxtStanzas splits a vendor/modules.txt file into its
+// per-module stanzas, each starting with a "# " header line (e.g.
+// "# cloudeng.io/foo v1.2.3") and running up to (exclusive of) the
+// next one. keys preserves the header lines in file order, for
+// deterministic merging.
+func splitModulesTxtStanzas(data string) (keys []string, stanzas map[string]string) {
+	stanzas = map[string]string{}
+	var curKey string
+	var curLines []string
+	flush := func() {
+		if curKey != "" {
+			stanzas[curKey] = strings.Join(curLines, "\n")
+			keys = append(keys, curKey)
+		}
+	}
+	for _, line := range strings.Split(data, "\n") {
+		if strings.HasPrefix(line, "# ") {
+			flush()
+			curKey = line
+			curLines = []string{line}
+			continue
+		}
+		if curKey == "" {
+			continue
+		}
+		curLines = append(curLines, line)
+	}
+	flush()
+	return keys, stanzas
+}
+
+// depKey identifies a single module version, as recorded in a go.sum
+// file.
+type depKey struct {
+	path    string
+	version string
+}
+
+// parseGoSum returns the set of module versions recorded in the go.sum
+// file at path, deduplicated and excluding the "/go.mod" hash entries
+// (which pin the go.mod file, not the module zip we actually want to
+// prewarm into the cache). Returns no entries, rather than an error, if
+// the module has no go.sum.
+func parseGoSum(path string) ([]depKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	seen := map[depKey]bool{}
+	var deps []depKey
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") {
+			continue
+		}
+		dep := depKey{path: fields[0], version: fields[1]}
+		if !seen[dep] {
+			seen[dep] = true
+			deps = append(deps, dep)
+		}
+	}
+	return deps, nil
+}
+
+func goEnv(ctx context.Context, name string) (string, error) {
 	var out strings.Builder
-	c := exec.CommandContext(ctx, "git", "rev-parse", "HEAD")
-	c.Dir = path
+	c := exec.CommandContext(ctx, "go", "env", name)
 	c.Stderr = os.Stderr
 	c.Stdout = &out
 	if err := c.Run(); err != nil {
 		return "", err
 	}
-	return strings.TrimSpace(out.String()[:8]), nil
+	return strings.TrimSpace(out.String()), nil
+}
+
+// prewarmAction populates $GOMODCACHE for every module in dirs, ahead
+// of a CI run where a cold module cache would otherwise dominate test
+// time. It unions the go.sum of every module first, so a dependency
+// required by several modules in this workspace is only ever
+// downloaded once rather than racing jobs concurrently downloading the
+// same module version, then downloads the remaining unique versions up
+// to jobs at a time and prints a summary of cache hits and bytes
+// fetched.
+func prewarmAction(ctx context.Context, dirs []string, jobs int) error {
+	gomodcache, err := goEnv(ctx, "GOMODCACHE")
+	if err != nil {
+		return fmt.Errorf("determining GOMODCACHE: %w", err)
+	}
+
+	owner := map[depKey]string{} // dep -> a module dir that can resolve it
+	for _, dir := range dirs {
+		deps, err := parseGoSum(filepath.Join(dir, "go.sum"))
+		if err != nil {
+			return fmt.Errorf("reading go.sum for %v: %w", dir, err)
+		}
+		for _, dep := range deps {
+			if _, ok := owner[dep]; !ok {
+				owner[dep] = dir
+			}
+		}
+	}
+
+	if jobs < 1 {
+		jobs = 1
+	}
+	var (
+		errs         errors.M
+		errsMu       sync.Mutex
+		hits, misses int
+		totalBytes   int64
+		statsMu      sync.Mutex
+		wg           sync.WaitGroup
+	)
+	sem := make(chan struct{}, jobs)
+	for dep, dir := range owner {
+		dep, dir := dep, dir
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			zipPath := filepath.Join(gomodcache, "cache", "download", filepath.FromSlash(dep.path), "@v", dep.version+".zip")
+			hit := false
+			if _, err := os.Stat(zipPath); err == nil {
+				hit = true
+			}
+			if err := runInDir(ctx, dir, "go", []string{"mod", "download", "-x", dep.path + "@" + dep.version}); err != nil {
+				errsMu.Lock()
+				errs.Append(fmt.Errorf("downloading %v@%v: %w", dep.path, dep.version, err))
+				errsMu.Unlock()
+				return
+			}
+			var size int64
+			if info, err := os.Stat(zipPath); err == nil {
+				size = info.Size()
+			}
+			statsMu.Lock()
+			if hit {
+				hits++
+			} else {
+				misses++
+				totalBytes += size
+			}
+			statsMu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if err := errs.Err(); err != nil {
+		return err
+	}
+
+	total := hits + misses
+	var ratio float64
+	if total > 0 {
+		ratio = float64(hits) / float64(total) * 100
+	}
+	fmt.Printf("prewarm: %d unique module versions, %d already cached, %d downloaded (%.1f%% hit ratio, %v downloaded)\n",
+		total, hits, misses, ratio, formatBytes(totalBytes))
+	return nil
+}
+
+// formatBytes renders n bytes as a human readable size, e.g. "12.3MiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for next := n / unit; next >= unit; next /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
 }
@@ -5,23 +5,39 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/exec"
 	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
+
+	"github.com/fsnotify/fsnotify"
 )
 
 var interval time.Duration
 var total time.Duration
 var initial time.Duration
 var verbose bool
+var containsFlag string
+var regexFlag string
+var jsonPathFlag string
+var anyFlag bool
+var execFlag string
 
 var errInt = errors.New("interrupted")
+var errSatisfied = errors.New("another file already satisfied -any")
+var errWatchUnavailable = errors.New("fsnotify watcher unavailable")
 
 func now() string {
 	return time.Now().Format(time.DateTime)
@@ -32,8 +48,19 @@ func main() {
 	flag.DurationVar(&total, "total", time.Second*10, "Total duration to run")
 	flag.DurationVar(&initial, "initial", 0, "Initial delay before starting")
 	flag.BoolVar(&verbose, "verbose", false, "Enable verbose output")
+	flag.StringVar(&containsFlag, "contains", "", "require the file's contents to contain this substring")
+	flag.StringVar(&regexFlag, "regex", "", "require the file's contents to match this regular expression")
+	flag.StringVar(&jsonPathFlag, "jsonpath", "", "<expr>=<value>; require the file to be JSON whose value at expr (a dotted path, e.g. .status or .services[0].name) equals value")
+	flag.BoolVar(&anyFlag, "any", false, "succeed as soon as any one of the listed files is satisfied, rather than requiring all of them")
+	flag.StringVar(&execFlag, "exec", "", "command to run, via 'sh -c', once the wait condition is met")
 	flag.Parse()
 
+	pred, err := buildPredicate(containsFlag, regexFlag, jsonPathFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
 	ctx, cancel := context.WithCancelCause(context.Background())
 	defer cancel(context.Canceled)
 
@@ -53,53 +80,309 @@ func main() {
 		fmt.Printf("%v: initial delay of %v\n", now(), initial)
 		time.Sleep(initial)
 	}
+
+	if !runWaits(ctx, cancel, files, pred) {
+		os.Exit(1)
+	}
+
+	if execFlag != "" {
+		cmd := exec.Command("sh", "-c", execFlag)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "error: running -exec command %q: %v\n", execFlag, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// runWaits waits for files per -any's semantics, returning whether the
+// overall condition was satisfied: with -any, as soon as one file is
+// satisfied (cancelling the wait for the rest); otherwise only once
+// every file is satisfied.
+func runWaits(ctx context.Context, cancel context.CancelCauseFunc, files []string, pred predicate) bool {
 	var wg sync.WaitGroup
 	wg.Add(len(files))
 	errCh := make(chan error, len(files))
 	for _, file := range files {
+		file := file
 		go func() {
-			errCh <- waitForFile(ctx, file, interval, total)
-			wg.Done()
+			defer wg.Done()
+			err := waitForFile(ctx, file, interval, total, pred)
+			if err == nil && anyFlag {
+				cancel(errSatisfied)
+			}
+			errCh <- err
 		}()
 	}
 	wg.Wait()
 	close(errCh)
-	exitCode := 0
+
+	satisfiedCount := 0
 	for err := range errCh {
+		if err == nil {
+			satisfiedCount++
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+	}
+	if anyFlag {
+		return satisfiedCount > 0
+	}
+	return satisfiedCount == len(files)
+}
+
+// predicate is the optional content condition a file's contents must
+// satisfy, in addition to simply existing; none reports true when no
+// such condition was configured, in which case callers should fall
+// back to a plain existence check rather than reading the file.
+type predicate struct {
+	contains string
+	regex    *regexp.Regexp
+	jsonPath string
+	jsonVal  string
+}
+
+func buildPredicate(contains, pattern, jsonpath string) (predicate, error) {
+	var p predicate
+	p.contains = contains
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "error: %v\n", err)
-			exitCode = 1
+			return p, fmt.Errorf("invalid -regex %q: %w", pattern, err)
 		}
+		p.regex = re
 	}
-	if exitCode != 0 {
-		os.Exit(exitCode)
+	if jsonpath != "" {
+		expr, val, ok := strings.Cut(jsonpath, "=")
+		if !ok {
+			return p, fmt.Errorf("invalid -jsonpath %q: want <expr>=<value>", jsonpath)
+		}
+		p.jsonPath = expr
+		p.jsonVal = val
 	}
+	return p, nil
 }
 
-func waitForFile(ctx context.Context, path string, interval, total time.Duration) error {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+func (p predicate) none() bool {
+	return p.contains == "" && p.regex == nil && p.jsonPath == ""
+}
+
+func (p predicate) match(data []byte) (bool, error) {
+	if p.contains != "" && !bytes.Contains(data, []byte(p.contains)) {
+		return false, nil
+	}
+	if p.regex != nil && !p.regex.Match(data) {
+		return false, nil
+	}
+	if p.jsonPath != "" {
+		val, ok, err := jsonPathValue(data, p.jsonPath)
+		if err != nil {
+			return false, err
+		}
+		if !ok || val != p.jsonVal {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// jsonPathValue parses data as JSON and navigates a dotted path such as
+// ".status" or ".services[0].name" (a leading "." refers to the root),
+// returning the value found there rendered as a string: a JSON string
+// value is returned unquoted, anything else is returned as its JSON
+// text. ok is false if expr doesn't resolve to anything in data.
+func jsonPathValue(data []byte, expr string) (value string, ok bool, err error) {
+	var root any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return "", false, fmt.Errorf("parsing JSON: %w", err)
+	}
+	cur := root
+	expr = strings.TrimPrefix(expr, ".")
+	if expr != "" {
+		for _, part := range strings.Split(expr, ".") {
+			key, idx, hasIdx := splitJSONPathSegment(part)
+			m, isMap := cur.(map[string]any)
+			if !isMap {
+				return "", false, nil
+			}
+			cur, ok = m[key]
+			if !ok {
+				return "", false, nil
+			}
+			if hasIdx {
+				arr, isArr := cur.([]any)
+				if !isArr || idx < 0 || idx >= len(arr) {
+					return "", false, nil
+				}
+				cur = arr[idx]
+			}
+		}
+	}
+	switch v := cur.(type) {
+	case string:
+		return v, true, nil
+	case nil:
+		return "", true, nil
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", false, fmt.Errorf("rendering value at %q: %w", expr, err)
+		}
+		return string(b), true, nil
+	}
+}
+
+// splitJSONPathSegment splits a path segment like "services[0]" into
+// its key and array index.
+func splitJSONPathSegment(part string) (key string, idx int, hasIdx bool) {
+	i := strings.IndexByte(part, '[')
+	if i < 0 || !strings.HasSuffix(part, "]") {
+		return part, 0, false
+	}
+	n, err := strconv.Atoi(part[i+1 : len(part)-1])
+	if err != nil {
+		return part, 0, false
+	}
+	return part[:i], n, true
+}
+
+// checkFile reports whether path currently satisfies pred: existence
+// alone if pred is empty (avoiding reading a file's contents when no
+// predicate was given), or pred.match of its contents otherwise.
+// Returns false, nil if path doesn't exist yet.
+func checkFile(path string, pred predicate) (bool, error) {
+	if pred.none() {
+		if _, err := os.Stat(path); err != nil {
+			if os.IsNotExist(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return pred.match(data)
+}
 
+// waitForFile waits until path satisfies pred (or just exists, if pred
+// is empty), preferring an fsnotify watch on path's parent directory
+// for near-instant reaction to create/write events, and falling back
+// to polling path every interval if the watcher fails to initialize.
+func waitForFile(ctx context.Context, path string, interval, total time.Duration, pred predicate) error {
 	ctx, cancel := context.WithTimeout(ctx, total)
 	defer cancel()
 
-	if _, err := os.Stat(path); err == nil {
+	if ok, err := checkFile(path, pred); err != nil {
+		return fmt.Errorf("%v: checking file %q: %w", now(), path, err)
+	} else if ok {
+		return nil
+	}
+
+	err := watchFile(ctx, path, pred)
+	if !errors.Is(err, errWatchUnavailable) {
+		return err
+	}
+	if verbose {
+		fmt.Printf("%v: %q: fsnotify unavailable, falling back to polling\n", now(), path)
+	}
+	return pollForFile(ctx, path, interval, pred)
+}
+
+// watchFile watches path's parent directory via fsnotify, re-checking
+// pred against path on every create/write event that names it. It
+// returns errWatchUnavailable (wrapping the underlying cause) if the
+// watcher can't be set up, so the caller can fall back to polling.
+func watchFile(ctx context.Context, path string, pred predicate) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("%w: %v", errWatchUnavailable, err)
+	}
+	defer watcher.Close()
+
+	dir := filepath.Dir(path)
+	if err := watcher.Add(dir); err != nil {
+		return fmt.Errorf("%w: watching %q: %v", errWatchUnavailable, dir, err)
+	}
+	if verbose {
+		fmt.Printf("%v: watching %q for %q\n", now(), dir, filepath.Base(path))
+	}
+
+	// The watch may have raced the file's creation or latest write, so
+	// check once more now that it's in place.
+	if ok, err := checkFile(path, pred); err != nil {
+		return fmt.Errorf("%v: checking file %q: %w", now(), path, err)
+	} else if ok {
 		return nil
 	}
 
+	for {
+		select {
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return fmt.Errorf("%w: watcher closed", errWatchUnavailable)
+			}
+			if filepath.Clean(ev.Name) != filepath.Clean(path) {
+				continue
+			}
+			if !ev.Has(fsnotify.Create) && !ev.Has(fsnotify.Write) {
+				continue
+			}
+			matched, err := checkFile(path, pred)
+			if err != nil {
+				return fmt.Errorf("%v: checking file %q: %w", now(), path, err)
+			}
+			if matched {
+				fmt.Printf("%v: %q: satisfied\n", now(), path)
+				return nil
+			}
+		case werr, ok := <-watcher.Errors:
+			if !ok {
+				return fmt.Errorf("%w: watcher closed", errWatchUnavailable)
+			}
+			if verbose {
+				fmt.Printf("%v: watcher error for %q: %v\n", now(), path, werr)
+			}
+		case <-ctx.Done():
+			if cause := context.Cause(ctx); cause == errInt || cause == errSatisfied {
+				return nil
+			}
+			return fmt.Errorf("%v: waiting for file %q: %v", now(), path, ctx.Err())
+		}
+	}
+}
+
+// pollForFile is the fallback used when an fsnotify watch can't be
+// set up: it checks pred against path every interval until it's
+// satisfied or ctx is done.
+func pollForFile(ctx context.Context, path string, interval time.Duration, pred predicate) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
 	for {
 		fmt.Printf("%v: waiting for file %q\n", now(), path)
 		select {
 		case <-ticker.C:
-			if _, err := os.Stat(path); err == nil {
-				fmt.Printf("%v: %q: exists\n", now(), path)
+			matched, err := checkFile(path, pred)
+			if err != nil {
+				return fmt.Errorf("%v: checking file %q: %w", now(), path, err)
+			}
+			if matched {
+				fmt.Printf("%v: %q: satisfied\n", now(), path)
 				return nil
 			}
 			if verbose {
 				fmt.Printf("%v: waiting for file %q\n", now(), path)
 			}
 		case <-ctx.Done():
-			if context.Cause(ctx) == errInt {
+			if cause := context.Cause(ctx); cause == errInt || cause == errSatisfied {
 				return nil
 			}
 			return fmt.Errorf("%v: waiting for file %q: %v", now(), path, ctx.Err())